@@ -2,17 +2,26 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"slices"
 	"time"
 
+	"github.com/sergioneiravargas/template-go/pkg/config"
 	"github.com/sergioneiravargas/template-go/pkg/core/auth"
-	"github.com/sergioneiravargas/template-go/pkg/framework/cache"
+	"github.com/sergioneiravargas/template-go/pkg/framework"
+	"github.com/sergioneiravargas/template-go/pkg/framework/compress"
+	"github.com/sergioneiravargas/template-go/pkg/framework/health"
+	"github.com/sergioneiravargas/template-go/pkg/framework/httputil"
+	"github.com/sergioneiravargas/template-go/pkg/framework/idempotency"
+	"github.com/sergioneiravargas/template-go/pkg/framework/inflight"
 	"github.com/sergioneiravargas/template-go/pkg/framework/log"
+	"github.com/sergioneiravargas/template-go/pkg/framework/metrics"
+	"github.com/sergioneiravargas/template-go/pkg/framework/ratelimit"
+	"github.com/sergioneiravargas/template-go/pkg/framework/server"
 	"github.com/sergioneiravargas/template-go/pkg/framework/sql"
+	"github.com/sergioneiravargas/template-go/pkg/framework/timeout"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
@@ -21,121 +30,174 @@ import (
 )
 
 func main() {
+	appConf, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
 	app := fx.New(
+		fx.Supply(appConf),
+		appOptions(),
+		fx.StopTimeout(stopTimeout(appConf.App.ShutdownGracePeriod)),
+		fx.NopLogger,
+	)
+	if err := app.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	app.Run()
+}
+
+// Composes the application from the reusable modules, expecting a config.Config
+func appOptions() fx.Option {
+	return fx.Options(
 		fx.Provide(
-			newAppConf,
-			newSQLDB,
-			newLogger,
-			newAuthService,
+			newFrameworkConf,
+			newAuthModuleConf,
 			newHTTPHandler,
 		),
+		framework.Module,
+		auth.Module,
 		fx.Invoke(configureLifecycleHooks),
-		fx.NopLogger,
 	)
-
-	app.Run()
 }
 
 func configureLifecycleHooks(
 	lc fx.Lifecycle,
+	appConf config.Config,
 	handler http.Handler,
-	db *sql.DB,
+	dbPair sql.DBPair,
+) {
+	httpServer := &http.Server{
+		Addr:    ":3000",
+		Handler: handler,
+	}
+
+	appendServerHooks(lc, httpServer, appConf.App.ShutdownGracePeriod, dbPair.Close)
+}
+
+// Extra time given to the fx stop hooks on top of the shutdown grace period,
+// so fx does not cancel them before the server finishes draining
+const stopTimeoutMargin = 5 * time.Second
+
+// Returns the fx stop timeout needed to honor the shutdown grace period
+func stopTimeout(gracePeriod time.Duration) time.Duration {
+	return gracePeriod + stopTimeoutMargin
+}
+
+// Starts the server with the application and shuts it down within the grace
+// period on stop, running the closers afterwards
+func appendServerHooks(
+	lc fx.Lifecycle,
+	httpServer *http.Server,
+	gracePeriod time.Duration,
+	closers ...func() error,
 ) {
 	lc.Append(fx.Hook{
 		OnStart: func(context.Context) error {
-			go http.ListenAndServe(":3000", handler)
+			go httpServer.ListenAndServe()
 
 			return nil
 		},
-		OnStop: func(context.Context) error {
-			if err := db.Close(); err != nil {
-				return err
-			}
-
-			return nil
+		OnStop: func(ctx context.Context) error {
+			return closeAll(append(
+				[]func() error{func() error {
+					return server.Shutdown(ctx, httpServer, gracePeriod)
+				}},
+				closers...,
+			)...)
 		},
 	})
 }
 
-type AppConf struct {
-	Name string
-	Env  string
-
-	SQLConf  sql.Conf
-	AuthConf auth.Conf
-}
-
-func newAppConf() AppConf {
-	// App configuration
-	appName := os.Getenv("APP_NAME")
-	if appName == "" {
-		panic("missing application name")
-	}
-
-	appEnv := os.Getenv("APP_ENV")
-	supportedEnvs := []string{
-		"prod",
-		"dev",
-	}
-	if !slices.Contains(supportedEnvs, appEnv) {
-		panic(fmt.Sprintf("unsupported application environment \"%s\"", appEnv))
-	}
-
-	// SQL configuration
-	sqlConf := sql.Conf{
-		Host:     os.Getenv("SQL_HOST"),
-		Port:     os.Getenv("SQL_PORT"),
-		User:     os.Getenv("SQL_USER"),
-		Password: os.Getenv("SQL_PASSWORD"),
-		Name:     os.Getenv("SQL_DATABASE"),
+// Runs every close function regardless of earlier failures, joining their errors
+func closeAll(closers ...func() error) error {
+	var errs []error
+	for _, closer := range closers {
+		errs = append(errs, closer())
 	}
 
-	// Auth configuration
-	keySet, err := auth.FetchKeySet(os.Getenv("AUTH_KEYSET_URL"))
-	if err != nil {
-		panic(err)
-	}
-
-	authConf := auth.Conf{
-		KeySet:    keySet,
-		DomainURL: os.Getenv("AUTH_DOMAIN_URL"),
-	}
-
-	return AppConf{
-		Name:     appName,
-		Env:      appEnv,
-		SQLConf:  sqlConf,
-		AuthConf: authConf,
-	}
+	return errors.Join(errs...)
 }
 
+const (
+	// Scopes required by the debug routes
+	debugReadScope  = "debug:read"
+	debugWriteScope = "debug:write"
+)
+
 func newHTTPHandler(
-	appConf AppConf,
+	lc fx.Lifecycle,
+	appConf config.Config,
 	logger *log.Logger,
 	authService *auth.Service,
+	dbPair sql.DBPair,
 ) http.Handler {
 	r := chi.NewRouter()
+	inFlightCounter := inflight.NewCounter()
+	appMetrics := metrics.New()
+	ipRateLimiter := ratelimit.NewLimiter(20, 40)
+	lc.Append(fx.StopHook(ipRateLimiter.Close))
+	userRateLimiter := ratelimit.NewLimiter(10, 20)
+	lc.Append(fx.StopHook(userRateLimiter.Close))
+	idempotencyStore := idempotency.NewStore(24 * time.Hour)
+	lc.Append(fx.StopHook(idempotencyStore.Close))
 
 	// Middlewares
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
+	// Keeps the connection address for the rate limits before the forwarding headers override it
+	r.Use(ratelimit.ConnectionAddrMiddleware)
 	r.Use(middleware.RealIP)
-	r.Use(log.Middleware(appConf.Name, appConf.Env))
+	r.Use(log.Middleware(appConf.App.Name, appConf.App.Env))
+	r.Use(log.RequestLoggerMiddleware(logger))
+	r.Use(inflight.Middleware(inFlightCounter))
+	r.Use(metrics.Middleware(appMetrics))
+
+	// Health routes
+	r.Get("/healthz", health.Handler())
+	r.Get("/readyz", health.Handler(
+		health.Check{Name: "sql_writer", Check: dbPair.Writer.PingContext},
+		health.Check{Name: "sql_reader", Check: dbPair.Reader.PingContext},
+	))
+
+	// Metrics routes
+	r.Handle("/metrics", appMetrics.Handler())
+
+	// Debug routes
+	r.Group(func(r chi.Router) {
+		// Middlewares
+		r.Use(auth.Middleware(authService))
+
+		// Routes
+		r.With(auth.RequireScopes(debugReadScope)).Get("/debug/inflight", inflight.Handler(inFlightCounter))
+		r.With(auth.RequireScopes(debugReadScope)).Get("/debug/log-level", log.LevelHandler(logger))
+		r.With(auth.RequireScopes(debugWriteScope)).Put("/debug/log-level", log.LevelHandler(logger))
+	})
 
 	// API routes
 	r.Group(func(r chi.Router) {
 		// Middlewares
-		r.Use(cors.Handler(cors.Options{
-			AllowedOrigins: []string{"*"},
-			AllowedMethods: []string{"HEAD", "GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders: []string{"Accept", "Authorization", "Content-Type"},
-		}))
+		// Limits by IP before authenticating so invalid token floods are limited too
+		r.Use(ratelimit.LimiterMiddleware(ipRateLimiter, ratelimit.IPKey))
+		r.Use(cors.Handler(appConf.App.CORS.Options()))
 		r.Use(auth.Middleware(authService))
+		r.Use(ratelimit.LimiterMiddleware(userRateLimiter, clientKey))
+		r.Use(timeout.Middleware(30 * time.Second))
+		r.Use(compress.Middleware(appConf.App.CompressionLevel))
+		// Stores uncompressed responses so replays negotiate their own encoding
+		r.Use(idempotency.Middleware(idempotencyStore, clientKey))
 
 		// Routes
 		r.Route("/api/v1", func(r chi.Router) {
 			r.Get("/hello-world", func(w http.ResponseWriter, r *http.Request) {
-				logger.Info("HTTP route reached", struct {
+				requestLogger, found := log.FromRequest(r)
+				if !found {
+					requestLogger = logger
+				}
+				requestLogger.Info("HTTP route reached", struct {
 					RoutePath string `json:"route_path"`
 				}{
 					RoutePath: r.URL.Path,
@@ -143,21 +205,15 @@ func newHTTPHandler(
 
 				userInfo, found := auth.UserInfoFromRequest(r)
 				if !found {
-					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeInternalError, "Internal server error")
 					return
 				}
 
-				body, err := json.Marshal(struct {
+				httputil.WriteJSON(w, http.StatusOK, struct {
 					Message string `json:"message"`
 				}{
 					Message: fmt.Sprintf("Hello, %s!", userInfo.ID),
 				})
-				if err != nil {
-					http.Error(w, "Internal server error", http.StatusInternalServerError)
-					return
-				}
-
-				w.Write(body)
 			})
 		})
 	})
@@ -166,7 +222,11 @@ func newHTTPHandler(
 	r.Group(func(r chi.Router) {
 		// Routes
 		r.Get("/hello-world", func(w http.ResponseWriter, r *http.Request) {
-			logger.Info("HTTP route reached", struct {
+			requestLogger, found := log.FromRequest(r)
+			if !found {
+				requestLogger = logger
+			}
+			requestLogger.Info("HTTP route reached", struct {
 				RoutePath string `json:"route_path"`
 			}{
 				RoutePath: r.URL.Path,
@@ -179,35 +239,27 @@ func newHTTPHandler(
 	return r
 }
 
-func newSQLDB(
-	appConf AppConf,
-) *sql.DB {
-	return sql.NewDB(
-		appConf.SQLConf,
-	)
-}
-
-func newLogger(
-	appConf AppConf,
-) *log.Logger {
-	handler := log.NewHandler(os.Stdout, appConf.Env)
+// Identifies the client by authenticated user, or by IP otherwise
+func clientKey(r *http.Request) string {
+	if userInfo, found := auth.UserInfoFromRequest(r); found {
+		return "user:" + userInfo.ID
+	}
 
-	return log.NewLogger(
-		appConf.Name,
-		handler,
-	)
+	return "ip:" + ratelimit.IPKey(r)
 }
 
-func newAuthService(
-	appConf AppConf,
-) *auth.Service {
-	userInfoCache := cache.New[string, *auth.UserInfo](
-		cache.WithTTL[string, *auth.UserInfo](10*time.Minute),
-		cache.WithCleanupInterval[string, *auth.UserInfo](30*time.Second),
-	)
+func newFrameworkConf(
+	appConf config.Config,
+) framework.Conf {
+	return framework.Conf{
+		Name:    appConf.App.Name,
+		Env:     appConf.App.Env,
+		SQLConf: appConf.SQL,
+	}
+}
 
-	return auth.NewService(
-		appConf.AuthConf,
-		auth.ServiceWithUserInfoCache(userInfoCache),
-	)
+func newAuthModuleConf(
+	appConf config.Config,
+) auth.ModuleConf {
+	return appConf.Auth
 }
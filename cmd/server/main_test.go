@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/config"
+	"github.com/sergioneiravargas/template-go/pkg/core/auth"
+	"github.com/sergioneiravargas/template-go/pkg/framework/log"
+	"github.com/sergioneiravargas/template-go/pkg/framework/sql"
+
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+)
+
+func TestCloseAll(t *testing.T) {
+	errServer := errors.New("server shutdown failed")
+	errCache := errors.New("cache close failed")
+
+	var closed []string
+	closer := func(name string, err error) func() error {
+		return func() error {
+			closed = append(closed, name)
+			return err
+		}
+	}
+
+	err := closeAll(
+		closer("server", errServer),
+		closer("sql", nil),
+		closer("cache", errCache),
+	)
+
+	expectedClosed := []string{"server", "sql", "cache"}
+	if !slices.Equal(closed, expectedClosed) {
+		t.Errorf("expected closers %v to run, got %v", expectedClosed, closed)
+	}
+	if !errors.Is(err, errServer) || !errors.Is(err, errCache) {
+		t.Errorf("expected joined errors, got '%v'", err)
+	}
+
+	if err := closeAll(closer("sql", nil)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAppOptions(t *testing.T) {
+	err := fx.ValidateApp(
+		fx.Supply(config.Config{}),
+		appOptions(),
+	)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func newTestHTTPHandler(t *testing.T) (http.Handler, func(scope string) string) {
+	t.Helper()
+
+	userInfoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"sub":"user_1"}`))
+	}))
+	t.Cleanup(userInfoServer.Close)
+
+	secret := []byte("secret")
+	authService := auth.NewService(auth.Conf{
+		DomainURL: userInfoServer.URL,
+		Secret:    secret,
+	})
+
+	appConf := config.Config{
+		App: config.AppConf{
+			Name:             "test",
+			Env:              "dev",
+			CompressionLevel: config.DefaultCompressionLevel,
+		},
+	}
+	dbPair := sql.NewDBPair(sql.Conf{})
+	t.Cleanup(func() { dbPair.Close() })
+
+	lc := fxtest.NewLifecycle(t)
+	t.Cleanup(lc.RequireStop)
+
+	handler := newHTTPHandler(lc, appConf, log.NewNopLogger(), authService, dbPair)
+
+	newToken := func(scope string) string {
+		token, err := auth.GenerateTokenHS256(auth.MapClaims{
+			"sub":   "user_1",
+			"scope": scope,
+			"exp":   time.Now().Add(time.Minute).Unix(),
+		}, secret)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return token
+	}
+
+	return handler, newToken
+}
+
+func TestDebugRoutesRequireScopes(t *testing.T) {
+	handler, newToken := newTestHTTPHandler(t)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		token        string
+		expectedCode int
+	}{
+		{"inflight without token", http.MethodGet, "/debug/inflight", "", http.StatusUnauthorized},
+		{"inflight with write scope", http.MethodGet, "/debug/inflight", newToken(debugWriteScope), http.StatusForbidden},
+		{"inflight with read scope", http.MethodGet, "/debug/inflight", newToken(debugReadScope), http.StatusOK},
+		{"log level without token", http.MethodGet, "/debug/log-level", "", http.StatusUnauthorized},
+		{"log level update without token", http.MethodPut, "/debug/log-level", "", http.StatusUnauthorized},
+		{"log level update with read scope", http.MethodPut, "/debug/log-level", newToken(debugReadScope), http.StatusForbidden},
+		{"log level with read scope", http.MethodGet, "/debug/log-level", newToken(debugReadScope), http.StatusOK},
+		{"log level update with write scope", http.MethodPut, "/debug/log-level", newToken(debugWriteScope), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"level":"INFO"}`))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+
+			if res.Code != tt.expectedCode {
+				t.Errorf("expected status code %d, got %d", tt.expectedCode, res.Code)
+			}
+		})
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	gracePeriod := 30 * time.Second
+	if timeout := stopTimeout(gracePeriod); timeout <= gracePeriod {
+		t.Errorf("expected stop timeout to exceed the grace period '%s', got '%s'", gracePeriod, timeout)
+	}
+}
+
+func TestAppendServerHooksUsesGracePeriod(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	reached := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	httpServer := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(reached)
+			<-release
+		}),
+	}
+
+	gracePeriod := 200 * time.Millisecond
+	closed := false
+	lc := fxtest.NewLifecycle(t)
+	appendServerHooks(lc, httpServer, gracePeriod, func() error {
+		closed = true
+
+		return nil
+	})
+	lc.RequireStart()
+
+	go func() {
+		for {
+			res, err := http.Get("http://" + addr)
+			if err == nil {
+				res.Body.Close()
+
+				return
+			}
+			select {
+			case <-reached:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+	<-reached
+
+	// Mirrors how fx bounds the stop hooks with the configured stop timeout
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout(gracePeriod))
+	defer cancel()
+
+	start := time.Now()
+	err = lc.Stop(ctx)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error '%s', got '%v'", context.DeadlineExceeded, err)
+	}
+	if elapsed < gracePeriod {
+		t.Errorf("expected shutdown to wait for the grace period '%s', took '%s'", gracePeriod, elapsed)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("expected shutdown to finish before the stop timeout, got '%v'", ctx.Err())
+	}
+	if !closed {
+		t.Errorf("expected closers to run after the server shutdown")
+	}
+}
+
+func TestAPIRoutesLimitUnauthenticatedRequests(t *testing.T) {
+	handler, _ := newTestHTTPHandler(t)
+
+	limited := false
+	for i := 0; i < 100 && !limited; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/hello-world", nil)
+		req.Header.Set("Authorization", "Bearer invalid")
+		// Spoofed forwarding headers don't escape the limit
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i))
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		limited = res.Code == http.StatusTooManyRequests
+	}
+
+	if !limited {
+		t.Errorf("expected requests with an invalid token to be rate limited")
+	}
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/core/auth"
+	"github.com/sergioneiravargas/template-go/pkg/framework/httpconf"
+	"github.com/sergioneiravargas/template-go/pkg/framework/sql"
+)
+
+var (
+	ErrMissingVariable = errors.New("missing environment variable")
+	ErrInvalidVariable = errors.New("invalid environment variable")
+)
+
+// Supported application environments
+var SupportedEnvs = []string{
+	"prod",
+	"dev",
+}
+
+const (
+	DefaultShutdownGracePeriod = 10 * time.Second
+	DefaultCompressionLevel    = 5
+)
+
+// Application configuration loaded from the environment
+type Config struct {
+	App  AppConf
+	SQL  sql.Conf
+	Auth auth.ModuleConf
+}
+
+type AppConf struct {
+	Name string
+	Env  string
+
+	ShutdownGracePeriod time.Duration
+	CompressionLevel    int
+	CORS                httpconf.CORSConf
+}
+
+// Loads the configuration from the environment, reporting every missing or
+// invalid variable at once
+func Load() (Config, error) {
+	l := &loader{}
+
+	// App configuration
+	appConf := AppConf{
+		Name:                l.required("APP_NAME"),
+		Env:                 l.oneOf("APP_ENV", SupportedEnvs),
+		ShutdownGracePeriod: l.duration("SHUTDOWN_GRACE_PERIOD", DefaultShutdownGracePeriod),
+		CompressionLevel:    l.intRange("COMPRESSION_LEVEL", DefaultCompressionLevel, 1, 9),
+	}
+	if appConf.Env != "" {
+		corsConf, err := httpconf.NewCORSConf(
+			appConf.Env,
+			os.Getenv("CORS_ALLOWED_ORIGINS"),
+			os.Getenv("CORS_ALLOWED_METHODS"),
+			os.Getenv("CORS_ALLOWED_HEADERS"),
+		)
+		l.check(err)
+		appConf.CORS = corsConf
+	}
+
+	// SQL configuration
+	sqlConf := sql.Conf{
+		Host:     l.required("SQL_HOST"),
+		Port:     l.port("SQL_PORT", true),
+		User:     l.required("SQL_USER"),
+		Password: os.Getenv("SQL_PASSWORD"),
+		Name:     l.required("SQL_DATABASE"),
+		SSLMode:  os.Getenv("SQL_SSL_MODE"),
+	}
+	if replicaHost := os.Getenv("SQL_REPLICA_HOST"); replicaHost != "" {
+		replicaConf := sqlConf
+		replicaConf.Host = replicaHost
+		if replicaPort := l.port("SQL_REPLICA_PORT", false); replicaPort != "" {
+			replicaConf.Port = replicaPort
+		}
+		sqlConf.Replica = &replicaConf
+	}
+
+	// Auth configuration
+	authConf := auth.ModuleConf{
+		Conf: auth.Conf{
+			DomainURL:        l.required("AUTH_DOMAIN_URL"),
+			ExpectedIssuer:   os.Getenv("AUTH_ISSUER"),
+			ExpectedAudience: httpconf.ParseList(os.Getenv("AUTH_AUDIENCE")),
+			RolesClaim:       os.Getenv("AUTH_ROLES_CLAIM"),
+		},
+		KeySetURL: l.required("AUTH_KEYSET_URL"),
+	}
+
+	if err := errors.Join(l.errs...); err != nil {
+		return Config{}, err
+	}
+
+	return Config{
+		App:  appConf,
+		SQL:  sqlConf,
+		Auth: authConf,
+	}, nil
+}
+
+// Reads environment variables collecting their errors
+type loader struct {
+	errs []error
+}
+
+func (l *loader) check(err error) {
+	if err != nil {
+		l.errs = append(l.errs, err)
+	}
+}
+
+func (l *loader) required(name string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		l.check(fmt.Errorf("%w \"%s\"", ErrMissingVariable, name))
+	}
+
+	return value
+}
+
+func (l *loader) invalid(name string, value string) {
+	l.check(fmt.Errorf("%w \"%s\": unsupported value \"%s\"", ErrInvalidVariable, name, value))
+}
+
+func (l *loader) oneOf(name string, values []string) string {
+	value := l.required(name)
+	if value != "" && !slices.Contains(values, value) {
+		l.invalid(name, value)
+		return ""
+	}
+
+	return value
+}
+
+func (l *loader) port(name string, required bool) string {
+	value := os.Getenv(name)
+	if value == "" {
+		if required {
+			l.required(name)
+		}
+		return ""
+	}
+
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		l.invalid(name, value)
+	}
+
+	return value
+}
+
+func (l *loader) duration(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		l.invalid(name, value)
+	}
+
+	return duration
+}
+
+func (l *loader) intRange(name string, defaultValue int, min int, max int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < min || number > max {
+		l.invalid(name, value)
+	}
+
+	return number
+}
@@ -0,0 +1,141 @@
+package config_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/config"
+)
+
+var completeEnv = map[string]string{
+	"APP_NAME":              "template",
+	"APP_ENV":               "prod",
+	"SHUTDOWN_GRACE_PERIOD": "25s",
+	"COMPRESSION_LEVEL":     "",
+	"CORS_ALLOWED_ORIGINS":  "https://app.example.com",
+	"CORS_ALLOWED_METHODS":  "",
+	"CORS_ALLOWED_HEADERS":  "",
+	"SQL_HOST":              "localhost",
+	"SQL_PORT":              "5432",
+	"SQL_USER":              "user",
+	"SQL_PASSWORD":          "password",
+	"SQL_DATABASE":          "database",
+	"SQL_SSL_MODE":          "",
+	"SQL_REPLICA_HOST":      "replica",
+	"SQL_REPLICA_PORT":      "",
+	"AUTH_DOMAIN_URL":       "https://auth.example.com",
+	"AUTH_KEYSET_URL":       "https://auth.example.com/.well-known/jwks.json",
+	"AUTH_ISSUER":           "",
+	"AUTH_AUDIENCE":         "api,admin",
+	"AUTH_ROLES_CLAIM":      "",
+}
+
+func setEnv(t *testing.T, overrides map[string]string) {
+	t.Helper()
+
+	for name, value := range completeEnv {
+		if override, found := overrides[name]; found {
+			value = override
+		}
+		t.Setenv(name, value)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	setEnv(t, nil)
+
+	conf, err := config.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.App.Name != "template" || conf.App.Env != "prod" {
+		t.Errorf("expected app 'template' in 'prod', got '%s' in '%s'", conf.App.Name, conf.App.Env)
+	}
+	if conf.App.ShutdownGracePeriod != 25*time.Second {
+		t.Errorf("expected grace period to be %s, got %s", 25*time.Second, conf.App.ShutdownGracePeriod)
+	}
+	if conf.App.CompressionLevel != config.DefaultCompressionLevel {
+		t.Errorf("expected default compression level %d, got %d", config.DefaultCompressionLevel, conf.App.CompressionLevel)
+	}
+	if conf.SQL.Replica == nil || conf.SQL.Replica.Host != "replica" || conf.SQL.Replica.Port != "5432" {
+		t.Errorf("expected replica to default to the writer port, got %+v", conf.SQL.Replica)
+	}
+	if len(conf.Auth.Conf.ExpectedAudience) != 2 {
+		t.Errorf("expected 2 audiences, got %v", conf.Auth.Conf.ExpectedAudience)
+	}
+	if conf.Auth.KeySetURL != completeEnv["AUTH_KEYSET_URL"] {
+		t.Errorf("expected key set URL '%s', got '%s'", completeEnv["AUTH_KEYSET_URL"], conf.Auth.KeySetURL)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	setEnv(t, map[string]string{
+		"SHUTDOWN_GRACE_PERIOD": "",
+		"COMPRESSION_LEVEL":     "9",
+	})
+
+	conf, err := config.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.App.ShutdownGracePeriod != config.DefaultShutdownGracePeriod {
+		t.Errorf("expected default grace period %s, got %s", config.DefaultShutdownGracePeriod, conf.App.ShutdownGracePeriod)
+	}
+	if conf.App.CompressionLevel != 9 {
+		t.Errorf("expected compression level 9, got %d", conf.App.CompressionLevel)
+	}
+}
+
+func TestLoadMissingVariables(t *testing.T) {
+	setEnv(t, map[string]string{
+		"APP_NAME":        "",
+		"SQL_HOST":        "",
+		"AUTH_KEYSET_URL": "",
+	})
+
+	_, err := config.Load()
+	if !errors.Is(err, config.ErrMissingVariable) {
+		t.Fatalf("expected error '%s', got '%v'", config.ErrMissingVariable, err)
+	}
+
+	for _, name := range []string{"APP_NAME", "SQL_HOST", "AUTH_KEYSET_URL"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected error to report '%s', got '%v'", name, err)
+		}
+	}
+}
+
+func TestLoadInvalidVariables(t *testing.T) {
+	setEnv(t, map[string]string{
+		"APP_ENV":               "staging",
+		"SHUTDOWN_GRACE_PERIOD": "soon",
+		"COMPRESSION_LEVEL":     "10",
+		"SQL_PORT":              "postgres",
+		"SQL_REPLICA_PORT":      "70000",
+	})
+
+	_, err := config.Load()
+	if !errors.Is(err, config.ErrInvalidVariable) {
+		t.Fatalf("expected error '%s', got '%v'", config.ErrInvalidVariable, err)
+	}
+
+	for _, name := range []string{"APP_ENV", "SHUTDOWN_GRACE_PERIOD", "COMPRESSION_LEVEL", "SQL_PORT", "SQL_REPLICA_PORT"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected error to report '%s', got '%v'", name, err)
+		}
+	}
+}
+
+func TestLoadMissingCORSOrigins(t *testing.T) {
+	setEnv(t, map[string]string{
+		"CORS_ALLOWED_ORIGINS": "",
+	})
+
+	if _, err := config.Load(); err == nil {
+		t.Errorf("expected an error when the CORS origins are missing in prod")
+	}
+}
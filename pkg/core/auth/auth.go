@@ -2,20 +2,27 @@ package auth
 
 import (
 	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"math/big"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 var (
 	ErrInvalidKeySet                 = errors.New("invalid keyset")
+	ErrKeySetRequestFailed           = errors.New("key set request failed")
+	ErrUserInfoRequestFailed         = errors.New("user info request failed")
 	ErrInvalidHeader                 = errors.New("invalid header")
 	ErrTokenMalformed                = errors.New("token is malformed")
 	ErrTokenExpired                  = errors.New("token is expired")
@@ -25,7 +32,14 @@ var (
 	ErrExponentCouldNotBeDecoded     = errors.New("exponent could not be decoded")
 	ErrInvalidToken                  = errors.New("invalid token")
 	ErrInvalidTokenClaims            = errors.New("invalid token claims")
+	ErrInvalidIssuer                 = errors.New("invalid token issuer")
+	ErrInvalidAudience               = errors.New("invalid token audience")
 	ErrRSAPublicKeyCouldNotBeDecoded = errors.New("rsa public key could not be decoded")
+	ErrCoordinateCouldNotBeDecoded   = errors.New("coordinate could not be decoded")
+	ErrUnsupportedCurve              = errors.New("unsupported curve")
+	ErrInvalidCurvePoint             = errors.New("invalid curve point")
+	ErrUnsupportedKeyType            = errors.New("unsupported key type")
+	ErrAlgorithmMismatch             = errors.New("token algorithm does not match the key algorithm")
 )
 
 // JSON Web Token (JWT)
@@ -34,14 +48,24 @@ type Token = jwt.Token
 // JWT Map Claims
 type MapClaims = jwt.MapClaims
 
+// JWT parser option
+type ParserOption = jwt.ParserOption
+
 // JSON Web Key (JWK)
 type Key struct {
 	Kid string `json:"kid"`
 	Alg string `json:"alg"`
 	Kty string `json:"kty"`
-	E   string `json:"e"`
-	N   string `json:"n"`
 	Use string `json:"use"`
+
+	// RSA key parameters
+	E string `json:"e,omitempty"`
+	N string `json:"n,omitempty"`
+
+	// EC key parameters
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
 }
 
 // JSON Web Key Set (JWKS)
@@ -51,7 +75,40 @@ type KeySet struct {
 
 // The user information contained in the OIDC claims
 type UserInfo struct {
-	ID string `json:"sub"`
+	ID    string   `json:"sub"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Extracts the roles from the claim at the given dot separated path
+func RolesFromClaims(claims MapClaims, path string) []string {
+	var value any = map[string]any(claims)
+	for _, key := range strings.Split(path, ".") {
+		object, valid := value.(map[string]any)
+		if !valid {
+			return nil
+		}
+
+		value = object[key]
+	}
+
+	values, valid := value.([]any)
+	if !valid {
+		return nil
+	}
+
+	roles := make([]string, 0, len(values))
+	for _, value := range values {
+		if role, valid := value.(string); valid {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles
+}
+
+// HTTP client used to reach the identity provider when none is given
+var defaultHTTPClient = &http.Client{
+	Timeout: 10 * time.Second,
 }
 
 // Fetches UserInfo from the given URL
@@ -59,19 +116,42 @@ func FetchUserInfo(
 	url string,
 	accessToken string,
 ) (*UserInfo, error) {
-	req, err := http.NewRequest("GET", url, nil)
+	return FetchUserInfoWithClient(defaultHTTPClient, url, accessToken)
+}
+
+// Fetches UserInfo from the given URL using the given HTTP client
+func FetchUserInfoWithClient(
+	httpClient *http.Client,
+	url string,
+	accessToken string,
+) (*UserInfo, error) {
+	return FetchUserInfoContext(context.Background(), httpClient, url, accessToken)
+}
+
+// Fetches UserInfo from the given URL using the given HTTP client, the request
+// is aborted when the context is done
+func FetchUserInfoContext(
+	ctx context.Context,
+	httpClient *http.Client,
+	url string,
+	accessToken string,
+) (*UserInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Add("Authorization", "Bearer "+accessToken)
 
-	httpClient := &http.Client{}
 	res, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("%w: status %d", ErrUserInfoRequestFailed, res.StatusCode)
+	}
+
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
 		return nil, err
@@ -87,12 +167,32 @@ func FetchUserInfo(
 
 // Fetches the key set from the given URL
 func FetchKeySet(url string) (KeySet, error) {
-	res, err := http.Get(url)
+	return FetchKeySetWithClient(defaultHTTPClient, url)
+}
+
+// Fetches the key set from the given URL using the given HTTP client
+func FetchKeySetWithClient(httpClient *http.Client, url string) (KeySet, error) {
+	return FetchKeySetContext(context.Background(), httpClient, url)
+}
+
+// Fetches the key set from the given URL using the given HTTP client, the
+// request is aborted when the context is done
+func FetchKeySetContext(ctx context.Context, httpClient *http.Client, url string) (KeySet, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return KeySet{}, err
+	}
+
+	res, err := httpClient.Do(req)
 	if err != nil {
 		return KeySet{}, err
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return KeySet{}, fmt.Errorf("%w: status %d", ErrKeySetRequestFailed, res.StatusCode)
+	}
+
 	var keySet KeySet
 	if err := json.NewDecoder(res.Body).Decode(&keySet); err != nil {
 		return KeySet{}, err
@@ -113,7 +213,7 @@ func TokenFromHeader(header string) (string, error) {
 }
 
 // Parses the token using the given JWKS
-func ParseToken(token string, keySet KeySet) (*Token, error) {
+func ParseToken(token string, keySet KeySet, opts ...ParserOption) (*Token, error) {
 	parsedToken, err := jwt.Parse(
 		token,
 		func(t *Token) (any, error) {
@@ -122,32 +222,184 @@ func ParseToken(token string, keySet KeySet) (*Token, error) {
 					continue
 				}
 
-				rsa, err := RSAPublicKey(key)
-				if err != nil {
-					return nil, ErrRSAPublicKeyCouldNotBeDecoded
+				if err := validateAlgorithm(t, key.Alg); err != nil {
+					return nil, err
 				}
 
-				return &rsa, nil
+				return PublicKey(key)
 			}
 
 			return nil, ErrInvalidKeySet
 		},
+		opts...,
 	)
 	if err != nil {
-		if errors.Is(err, jwt.ErrTokenMalformed) {
-			return nil, ErrTokenMalformed
-		} else if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, ErrTokenExpired
-		} else if errors.Is(err, jwt.ErrTokenNotValidYet) {
-			return nil, ErrTokenNotValidYet
-		}
+		return nil, tokenError(err)
+	}
 
-		return nil, ErrTokenCouldNotBeParsed
+	return parsedToken, nil
+}
+
+// Parses the token using the given decoded public keys
+func ParseTokenWithPublicKeys(token string, publicKeys *PublicKeys, opts ...ParserOption) (*Token, error) {
+	parsedToken, err := jwt.Parse(
+		token,
+		func(t *Token) (any, error) {
+			kid, valid := t.Header["kid"].(string)
+			if !valid {
+				return nil, ErrInvalidKeySet
+			}
+
+			key, found := publicKeys.keys[kid]
+			if !found {
+				return nil, ErrInvalidKeySet
+			}
+
+			if err := validateAlgorithm(t, key.alg); err != nil {
+				return nil, err
+			}
+
+			return key.key, key.err
+		},
+		opts...,
+	)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+
+	return parsedToken, nil
+}
+
+// Parses the token using the given shared secret, only HS256 signed tokens are
+// accepted to prevent algorithm confusion attacks
+func ParseTokenWithSecret(token string, secret []byte, opts ...ParserOption) (*Token, error) {
+	opts = append(opts, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+	parsedToken, err := jwt.Parse(
+		token,
+		func(t *Token) (any, error) {
+			return secret, nil
+		},
+		opts...,
+	)
+	if err != nil {
+		return nil, tokenError(err)
 	}
 
 	return parsedToken, nil
 }
 
+// Generates an HS256 signed token with the given claims and shared secret,
+// meant for internal service to service tokens
+func GenerateTokenHS256(claims MapClaims, secret []byte) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// Returns the signing algorithm declared by the token header, without
+// verifying the token
+func tokenAlgorithm(token string) string {
+	parsedToken, _, err := jwt.NewParser().ParseUnverified(token, MapClaims{})
+	if err != nil {
+		return ""
+	}
+
+	return parsedToken.Method.Alg()
+}
+
+// Checks the token algorithm against the key one, as the key decides the
+// algorithm, not the token
+func validateAlgorithm(t *Token, alg string) error {
+	if alg != "" && alg != t.Method.Alg() {
+		return ErrAlgorithmMismatch
+	}
+
+	return nil
+}
+
+// Maps the JWT library errors to the package errors
+func tokenError(err error) error {
+	if errors.Is(err, jwt.ErrTokenMalformed) {
+		return ErrTokenMalformed
+	} else if errors.Is(err, jwt.ErrTokenExpired) {
+		return ErrTokenExpired
+	} else if errors.Is(err, jwt.ErrTokenNotValidYet) {
+		return ErrTokenNotValidYet
+	} else if errors.Is(err, jwt.ErrTokenInvalidIssuer) {
+		return ErrInvalidIssuer
+	} else if errors.Is(err, jwt.ErrTokenInvalidAudience) {
+		return ErrInvalidAudience
+	}
+
+	return ErrTokenCouldNotBeParsed
+}
+
+// Extracts the public key from the given JWK according to its type
+func PublicKey(key Key) (any, error) {
+	switch key.Kty {
+	case "EC":
+		ecdsa, err := ECDSAPublicKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa, nil
+	case "RSA", "":
+		rsa, err := RSAPublicKey(key)
+		if err != nil {
+			return nil, ErrRSAPublicKeyCouldNotBeDecoded
+		}
+
+		return &rsa, nil
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+}
+
+// Extracts the ECDSA public key from the given JWK
+func ECDSAPublicKey(key Key) (ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	var pointCurve ecdh.Curve
+	switch key.Crv {
+	case "P-256":
+		curve, pointCurve = elliptic.P256(), ecdh.P256()
+	case "P-384":
+		curve, pointCurve = elliptic.P384(), ecdh.P384()
+	case "P-521":
+		curve, pointCurve = elliptic.P521(), ecdh.P521()
+	default:
+		return ecdsa.PublicKey{}, ErrUnsupportedCurve
+	}
+
+	xb, err := base64.RawURLEncoding.DecodeString(key.X)
+	if err != nil {
+		return ecdsa.PublicKey{}, ErrCoordinateCouldNotBeDecoded
+	}
+
+	yb, err := base64.RawURLEncoding.DecodeString(key.Y)
+	if err != nil {
+		return ecdsa.PublicKey{}, ErrCoordinateCouldNotBeDecoded
+	}
+
+	// Make sure the point is on the curve using its uncompressed encoding
+	size := (curve.Params().BitSize + 7) / 8
+	if len(xb) > size || len(yb) > size {
+		return ecdsa.PublicKey{}, ErrInvalidCurvePoint
+	}
+	point := make([]byte, 1+2*size)
+	point[0] = 4
+	copy(point[1+size-len(xb):1+size], xb)
+	copy(point[1+2*size-len(yb):], yb)
+	if _, err := pointCurve.NewPublicKey(point); err != nil {
+		return ecdsa.PublicKey{}, ErrInvalidCurvePoint
+	}
+
+	return ecdsa.PublicKey{
+		Curve: curve,
+		X:     big.NewInt(0).SetBytes(xb),
+		Y:     big.NewInt(0).SetBytes(yb),
+	}, nil
+}
+
 // Extracts the RSA public key from the given JWK
 func RSAPublicKey(key Key) (rsa.PublicKey, error) {
 	nb, err := base64.RawURLEncoding.DecodeString(key.N)
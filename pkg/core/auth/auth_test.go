@@ -0,0 +1,246 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/core/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Round tripper that records the requests and replies with canned responses
+type recordingTransport struct {
+	lock      sync.Mutex
+	requests  []*http.Request
+	responses map[string]string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+
+	rt.requests = append(rt.requests, req)
+
+	body, found := rt.responses[req.URL.String()]
+	if !found {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       io.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func (rt *recordingTransport) Requests() []*http.Request {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+
+	return rt.requests
+}
+
+func TestFetchKeySetWithClient(t *testing.T) {
+	_, keySet := newTestKey(t)
+	body, err := json.Marshal(keySet)
+	if err != nil {
+		t.Fatalf("unexpected error encoding key set: %v", err)
+	}
+
+	url := "https://idp.test/.well-known/jwks.json"
+	transport := &recordingTransport{
+		responses: map[string]string{url: string(body)},
+	}
+
+	fetchedKeySet, err := auth.FetchKeySetWithClient(&http.Client{Transport: transport}, url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fetchedKeySet.Keys) != 1 || fetchedKeySet.Keys[0].N != keySet.Keys[0].N {
+		t.Errorf("expected fetched key set to match the served one")
+	}
+
+	if requests := transport.Requests(); len(requests) != 1 || requests[0].URL.String() != url {
+		t.Errorf("expected a single request to '%s'", url)
+	}
+}
+
+func TestParseTokenECDSA(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error generating ECDSA key: %v", err)
+	}
+
+	keySet := auth.KeySet{
+		Keys: []auth.Key{
+			{
+				Kid: "ec_key",
+				Alg: "ES256",
+				Kty: "EC",
+				Use: "sig",
+				Crv: "P-256",
+				X:   base64.RawURLEncoding.EncodeToString(privateKey.X.FillBytes(make([]byte, 32))),
+				Y:   base64.RawURLEncoding.EncodeToString(privateKey.Y.FillBytes(make([]byte, 32))),
+			},
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	token.Header["kid"] = "ec_key"
+	signedToken, err := token.SignedString(privateKey)
+	if err != nil {
+		t.Fatalf("unexpected error signing token: %v", err)
+	}
+
+	parsedToken, err := auth.ParseToken(signedToken, keySet)
+	if err != nil {
+		t.Fatalf("expected ES256 token to be valid, got '%v'", err)
+	}
+	if !parsedToken.Valid {
+		t.Errorf("expected ES256 token to be valid")
+	}
+
+	// A token claiming another algorithm for the EC key is rejected
+	rsaPrivateKey, _ := newTestKey(t)
+	rsaToken := jwt.NewWithClaims(jwt.SigningMethodRS256, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	rsaToken.Header["kid"] = "ec_key"
+	signedRSAToken, err := rsaToken.SignedString(rsaPrivateKey)
+	if err != nil {
+		t.Fatalf("unexpected error signing token: %v", err)
+	}
+
+	if _, err := auth.ParseToken(signedRSAToken, keySet); err == nil {
+		t.Errorf("expected RS256 token to be rejected for an ES256 key")
+	}
+}
+
+func TestECDSAPublicKeyInvalidPoint(t *testing.T) {
+	coordinate := base64.RawURLEncoding.EncodeToString(make([]byte, 32))
+	_, err := auth.ECDSAPublicKey(auth.Key{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   coordinate,
+		Y:   coordinate,
+	})
+	if !errors.Is(err, auth.ErrInvalidCurvePoint) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrInvalidCurvePoint, err)
+	}
+
+	_, err = auth.ECDSAPublicKey(auth.Key{Kty: "EC", Crv: "P-192"})
+	if !errors.Is(err, auth.ErrUnsupportedCurve) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrUnsupportedCurve, err)
+	}
+}
+
+func BenchmarkParseToken(b *testing.B) {
+	privateKey, keySet := newTestKey(b)
+	token := signTestToken(b, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+
+	b.Run("KeySet", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := auth.ParseToken(token, keySet); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+
+	b.Run("PublicKeys", func(b *testing.B) {
+		publicKeys := auth.NewPublicKeys(keySet)
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := auth.ParseTokenWithPublicKeys(token, publicKeys); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+}
+
+func TestRolesFromClaims(t *testing.T) {
+	claims := auth.MapClaims{
+		"realm_access": map[string]any{
+			"roles": []any{"admin", "user"},
+		},
+		"groups": []any{"engineering", "support"},
+		"scope":  "openid",
+	}
+
+	tests := []struct {
+		path  string
+		roles []string
+	}{
+		{"realm_access.roles", []string{"admin", "user"}},
+		{"groups", []string{"engineering", "support"}},
+		{"scope", nil},
+		{"realm_access.missing", nil},
+		{"groups.nested", nil},
+	}
+
+	for _, test := range tests {
+		roles := auth.RolesFromClaims(claims, test.path)
+		if !slices.Equal(roles, test.roles) {
+			t.Errorf("expected roles at '%s' to be %v, got %v", test.path, test.roles, roles)
+		}
+	}
+}
+
+func TestFetchContextCancellation(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		},
+	))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := auth.FetchUserInfoContext(ctx, http.DefaultClient, server.URL, "token"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error to be '%v', got '%v'", context.DeadlineExceeded, err)
+	}
+	if _, err := auth.FetchKeySetContext(ctx, http.DefaultClient, server.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error to be '%v', got '%v'", context.DeadlineExceeded, err)
+	}
+	if _, err := auth.IntrospectTokenContext(ctx, http.DefaultClient, server.URL, "client_id", "client_secret", "token"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error to be '%v', got '%v'", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the requests to be aborted promptly, took %s", elapsed)
+	}
+}
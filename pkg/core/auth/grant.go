@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTokenRequestFailed = errors.New("token request failed")
+	ErrInvalidGrant       = errors.New("invalid grant")
+)
+
+// Response of the token endpoint
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	Scope        string `json:"scope,omitempty"`
+	ExpiresIn    int    `json:"expires_in"`
+
+	// Computed from ExpiresIn when the response is received
+	ExpiresAt time.Time `json:"-"`
+}
+
+// Error response of the token endpoint
+type tokenErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Requests new tokens to the given token endpoint using the refresh token grant,
+// authenticating with the client credentials. The identity provider may or may
+// not return a new refresh token
+func RefreshTokenWithClient(
+	httpClient *http.Client,
+	tokenURL string,
+	clientID string,
+	clientSecret string,
+	refreshToken string,
+) (TokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+	}
+
+	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var errorResponse tokenErrorResponse
+		if err := json.NewDecoder(res.Body).Decode(&errorResponse); err != nil {
+			return TokenResponse{}, fmt.Errorf("%w: status %d", ErrTokenRequestFailed, res.StatusCode)
+		}
+
+		if errorResponse.Error == "invalid_grant" {
+			return TokenResponse{}, fmt.Errorf("%w: %s", ErrInvalidGrant, errorResponse.ErrorDescription)
+		}
+
+		return TokenResponse{}, fmt.Errorf("%w: %s", ErrTokenRequestFailed, errorResponse.Error)
+	}
+
+	var tokenResponse TokenResponse
+	if err := json.NewDecoder(res.Body).Decode(&tokenResponse); err != nil {
+		return TokenResponse{}, fmt.Errorf("%w: %w", ErrTokenRequestFailed, err)
+	}
+	tokenResponse.ExpiresAt = time.Now().Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+
+	return tokenResponse, nil
+}
@@ -0,0 +1,76 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/core/auth"
+)
+
+func TestServiceRefreshToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/oauth/token" ||
+				r.PostFormValue("grant_type") != "refresh_token" ||
+				r.PostFormValue("client_id") != "client_id" ||
+				r.PostFormValue("client_secret") != "client_secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_request"})
+				return
+			}
+
+			if r.PostFormValue("refresh_token") != "refresh_token_1" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error":             "invalid_grant",
+					"error_description": "unknown or invalid refresh token",
+				})
+				return
+			}
+
+			json.NewEncoder(w).Encode(map[string]any{
+				"access_token":  "access_token_2",
+				"refresh_token": "refresh_token_2",
+				"token_type":    "Bearer",
+				"expires_in":    3600,
+			})
+		},
+	))
+	defer server.Close()
+
+	service := auth.NewService(auth.Conf{
+		DomainURL:    server.URL,
+		ClientID:     "client_id",
+		ClientSecret: "client_secret",
+	})
+
+	start := time.Now()
+	tokens, err := service.RefreshToken("refresh_token_1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tokens.AccessToken != "access_token_2" || tokens.RefreshToken != "refresh_token_2" {
+		t.Errorf("expected the new tokens to be returned, got %+v", tokens)
+	}
+	if tokens.ExpiresAt.Before(start.Add(time.Hour)) {
+		t.Errorf("expected tokens to expire in an hour, got %s", tokens.ExpiresAt)
+	}
+
+	if _, err := service.RefreshToken("refresh_token_unknown"); !errors.Is(err, auth.ErrInvalidGrant) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrInvalidGrant, err)
+	}
+
+	otherClientService := auth.NewService(auth.Conf{
+		DomainURL:    server.URL,
+		ClientID:     "other_client_id",
+		ClientSecret: "client_secret",
+	})
+	if _, err := otherClientService.RefreshToken("refresh_token_1"); !errors.Is(err, auth.ErrTokenRequestFailed) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrTokenRequestFailed, err)
+	}
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrIntrospectionFailed = errors.New("token introspection failed")
+	ErrTokenInactive       = errors.New("token is not active")
+)
+
+// Strategy used by the auth service to validate tokens
+type ValidationStrategy int
+
+const (
+	// Validate the tokens as JWTs using the key set
+	ValidationStrategyJWT ValidationStrategy = iota
+	// Validate the tokens with the introspection endpoint (RFC 7662)
+	ValidationStrategyIntrospection
+	// Validate the tokens as JWTs, falling back to the introspection endpoint
+	// for the tokens that are not JWTs (e.g. opaque tokens)
+	ValidationStrategyJWTWithIntrospectionFallback
+)
+
+// Introspects the token with the given endpoint (RFC 7662) authenticating with
+// the client credentials, returns the introspection response as claims when the
+// token is active
+func IntrospectTokenWithClient(
+	httpClient *http.Client,
+	introspectionURL string,
+	clientID string,
+	clientSecret string,
+	token string,
+) (MapClaims, error) {
+	return IntrospectTokenContext(
+		context.Background(),
+		httpClient,
+		introspectionURL,
+		clientID,
+		clientSecret,
+		token,
+	)
+}
+
+// Introspects the token like IntrospectTokenWithClient, the request is aborted
+// when the context is done
+func IntrospectTokenContext(
+	ctx context.Context,
+	httpClient *http.Client,
+	introspectionURL string,
+	clientID string,
+	clientSecret string,
+	token string,
+) (MapClaims, error) {
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", introspectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(clientID, clientSecret)
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, ErrIntrospectionFailed
+	}
+
+	var claims MapClaims
+	if err := json.NewDecoder(res.Body).Decode(&claims); err != nil {
+		return nil, ErrIntrospectionFailed
+	}
+
+	if active, _ := claims["active"].(bool); !active {
+		return nil, ErrTokenInactive
+	}
+
+	return claims, nil
+}
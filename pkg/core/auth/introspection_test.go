@@ -0,0 +1,208 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/core/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newIntrospectionServer(t *testing.T, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+
+			clientID, clientSecret, found := r.BasicAuth()
+			if !found || clientID != "client_id" || clientSecret != "client_secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			response := map[string]any{"active": false}
+			if r.PostFormValue("token") == "opaque_active" {
+				response = map[string]any{
+					"active": true,
+					"sub":    "user_1",
+					"scope":  "read:logs",
+					"iss":    "https://issuer.example.com",
+					"aud":    "api",
+					"jti":    "token_1",
+				}
+			}
+
+			json.NewEncoder(w).Encode(response)
+		},
+	))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestServiceIntrospection(t *testing.T) {
+	var calls atomic.Int32
+	server := newIntrospectionServer(t, &calls)
+
+	service := auth.NewService(auth.Conf{
+		ValidationStrategy: auth.ValidationStrategyIntrospection,
+		IntrospectionURL:   server.URL,
+		ClientID:           "client_id",
+		ClientSecret:       "client_secret",
+	})
+
+	if err := service.ValidateToken("opaque_active"); err != nil {
+		t.Errorf("expected active token to be valid, got '%v'", err)
+	}
+
+	claims, err := service.TokenClaims("opaque_active")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims["sub"] != "user_1" {
+		t.Errorf("expected subject to be 'user_1', got '%v'", claims["sub"])
+	}
+
+	if err := service.ValidateToken("opaque_inactive"); !errors.Is(err, auth.ErrTokenInactive) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrTokenInactive, err)
+	}
+
+	unauthorizedService := auth.NewService(auth.Conf{
+		ValidationStrategy: auth.ValidationStrategyIntrospection,
+		IntrospectionURL:   server.URL,
+		ClientID:           "client_id",
+		ClientSecret:       "wrong_secret",
+	})
+	if err := unauthorizedService.ValidateToken("opaque_active"); !errors.Is(err, auth.ErrIntrospectionFailed) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrIntrospectionFailed, err)
+	}
+}
+
+func TestServiceIntrospectionClaimsValidation(t *testing.T) {
+	var calls atomic.Int32
+	server := newIntrospectionServer(t, &calls)
+
+	conf := auth.Conf{
+		ValidationStrategy: auth.ValidationStrategyIntrospection,
+		IntrospectionURL:   server.URL,
+		ClientID:           "client_id",
+		ClientSecret:       "client_secret",
+		ExpectedIssuer:     "https://issuer.example.com",
+		ExpectedAudience:   []string{"api"},
+	}
+	if err := auth.NewService(conf).ValidateToken("opaque_active"); err != nil {
+		t.Errorf("expected token with the expected claims to be valid, got '%v'", err)
+	}
+
+	wrongIssuerConf := conf
+	wrongIssuerConf.ExpectedIssuer = "https://other.example.com"
+	if err := auth.NewService(wrongIssuerConf).ValidateToken("opaque_active"); !errors.Is(err, auth.ErrInvalidIssuer) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrInvalidIssuer, err)
+	}
+
+	wrongAudienceConf := conf
+	wrongAudienceConf.ExpectedAudience = []string{"other"}
+	if _, err := auth.NewService(wrongAudienceConf).TokenClaims("opaque_active"); !errors.Is(err, auth.ErrInvalidAudience) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrInvalidAudience, err)
+	}
+
+	store := auth.NewMemoryRevocationStore()
+	defer store.Close()
+	store.Revoke("token_1", time.Now().Add(time.Minute))
+	revokingService := auth.NewService(conf, auth.ServiceWithRevocationStore(store))
+	if err := revokingService.ValidateToken("opaque_active"); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrTokenRevoked, err)
+	}
+}
+
+func TestServiceJWTWithIntrospectionFallback(t *testing.T) {
+	var calls atomic.Int32
+	server := newIntrospectionServer(t, &calls)
+
+	privateKey, keySet := newTestKey(t)
+	service := auth.NewService(auth.Conf{
+		KeySet:             keySet,
+		ValidationStrategy: auth.ValidationStrategyJWTWithIntrospectionFallback,
+		IntrospectionURL:   server.URL,
+		ClientID:           "client_id",
+		ClientSecret:       "client_secret",
+	})
+
+	validToken := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	if err := service.ValidateToken(validToken); err != nil {
+		t.Errorf("expected JWT to be valid, got '%v'", err)
+	}
+	if count := calls.Load(); count != 0 {
+		t.Errorf("expected JWT not to be introspected, got %d calls", count)
+	}
+
+	// Expired JWTs are not rescued by the introspection
+	expiredToken := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	})
+	if err := service.ValidateToken(expiredToken); !errors.Is(err, auth.ErrTokenExpired) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrTokenExpired, err)
+	}
+
+	if err := service.ValidateToken("opaque_active"); err != nil {
+		t.Errorf("expected opaque token to be valid through introspection, got '%v'", err)
+	}
+	if err := service.ValidateToken("opaque_inactive"); !errors.Is(err, auth.ErrTokenInactive) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrTokenInactive, err)
+	}
+	if count := calls.Load(); count != 2 {
+		t.Errorf("expected 2 introspection calls, got %d", count)
+	}
+}
+
+func TestMiddlewareIntrospectsOnce(t *testing.T) {
+	var calls atomic.Int32
+	server := newIntrospectionServer(t, &calls)
+
+	userInfoServer := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]any{"sub": "user_1"})
+		},
+	))
+	t.Cleanup(userInfoServer.Close)
+
+	service := auth.NewService(auth.Conf{
+		DomainURL:          userInfoServer.URL,
+		ValidationStrategy: auth.ValidationStrategyIntrospection,
+		IntrospectionURL:   server.URL,
+		ClientID:           "client_id",
+		ClientSecret:       "client_secret",
+	})
+
+	handler := auth.Middleware(service)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			claims, found := auth.TokenClaimsFromRequest(r)
+			if !found || claims["sub"] != "user_1" {
+				t.Errorf("expected the introspected claims in the request's context, got '%v'", claims)
+			}
+		},
+	))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer opaque_active")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, res.Code)
+	}
+	if count := calls.Load(); count != 1 {
+		t.Errorf("expected 1 introspection call, got %d", count)
+	}
+}
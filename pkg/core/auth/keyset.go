@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Source of the key set used to validate tokens
+type KeySetProvider interface {
+	KeySet() KeySet
+}
+
+// Public keys of a key set decoded once and indexed by key ID, to avoid decoding
+// the JWKs on every token validation
+type PublicKeys struct {
+	keys map[string]publicKey
+}
+
+type publicKey struct {
+	alg string
+	key any
+	err error
+}
+
+// Decodes the public keys of the given key set, the decoding errors are
+// reported when validating the tokens signed with the offending keys
+func NewPublicKeys(keySet KeySet) *PublicKeys {
+	keys := make(map[string]publicKey, len(keySet.Keys))
+	for _, key := range keySet.Keys {
+		// Keep the first key when the IDs are repeated
+		if _, found := keys[key.Kid]; found {
+			continue
+		}
+
+		decodedKey, err := PublicKey(key)
+		keys[key.Kid] = publicKey{
+			alg: key.Alg,
+			key: decodedKey,
+			err: err,
+		}
+	}
+
+	return &PublicKeys{keys: keys}
+}
+
+// Error given to the refresh error handler when refetching the key set fails
+var ErrKeySetRefreshFailed = errors.New("key set refresh failed")
+
+// Key set that is periodically refetched to follow the key rotations of the
+// identity provider
+type RefreshingKeySet struct {
+	url        string
+	keySet     KeySet
+	publicKeys *PublicKeys
+	lock       sync.RWMutex
+
+	httpClient     *http.Client
+	onRefreshError func(error)
+
+	// Done once closed, aborting the refetch in progress if any
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type RefreshingKeySetOption func(*RefreshingKeySet)
+
+// Sets the HTTP client used to fetch the key set
+func KeySetWithHTTPClient(httpClient *http.Client) RefreshingKeySetOption {
+	return func(k *RefreshingKeySet) {
+		k.httpClient = httpClient
+	}
+}
+
+// Calls the given function when a periodic refetch fails, the current key set
+// is kept until the next refetch
+func KeySetWithRefreshErrorHandler(onRefreshError func(error)) RefreshingKeySetOption {
+	return func(k *RefreshingKeySet) {
+		k.onRefreshError = onRefreshError
+	}
+}
+
+// Fetches the key set from the given URL and refetches it at the given
+// interval until closed
+func NewRefreshingKeySet(
+	url string,
+	interval time.Duration,
+	opts ...RefreshingKeySetOption,
+) (*RefreshingKeySet, error) {
+	return NewRefreshingKeySetContext(context.Background(), url, interval, opts...)
+}
+
+// Creates a key set like NewRefreshingKeySet, the initial fetch is aborted when
+// the context is done
+func NewRefreshingKeySetContext(
+	ctx context.Context,
+	url string,
+	interval time.Duration,
+	opts ...RefreshingKeySetOption,
+) (*RefreshingKeySet, error) {
+	refreshingKeySet := &RefreshingKeySet{
+		url:        url,
+		httpClient: defaultHTTPClient,
+	}
+	for _, opt := range opts {
+		opt(refreshingKeySet)
+	}
+
+	keySet, err := refreshingKeySet.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	refreshingKeySet.keySet = keySet
+	refreshingKeySet.publicKeys = NewPublicKeys(keySet)
+	refreshingKeySet.ctx, refreshingKeySet.cancel = context.WithCancel(context.Background())
+
+	go refreshingKeySet.refresh(interval)
+
+	return refreshingKeySet, nil
+}
+
+// Returns the current key set
+func (k *RefreshingKeySet) KeySet() KeySet {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	return k.keySet
+}
+
+// Returns the decoded public keys of the current key set
+func (k *RefreshingKeySet) PublicKeys() *PublicKeys {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	return k.publicKeys
+}
+
+// Stops refetching the key set
+func (k *RefreshingKeySet) Close() {
+	k.cancel()
+}
+
+// Fetches the key set, rejecting the key sets without keys so they never
+// replace the current one
+func (k *RefreshingKeySet) fetch(ctx context.Context) (KeySet, error) {
+	keySet, err := FetchKeySetContext(ctx, k.httpClient, k.url)
+	if err != nil {
+		return KeySet{}, err
+	}
+
+	if len(keySet.Keys) == 0 {
+		return KeySet{}, fmt.Errorf("%w: no keys", ErrInvalidKeySet)
+	}
+
+	return keySet, nil
+}
+
+func (k *RefreshingKeySet) refresh(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-k.ctx.Done():
+			return
+		case <-ticker.C:
+			// Keep the current key set until the next refresh if the fetch fails
+			keySet, err := k.fetch(k.ctx)
+			if err != nil {
+				if k.onRefreshError != nil && k.ctx.Err() == nil {
+					k.onRefreshError(fmt.Errorf("%w: %w", ErrKeySetRefreshFailed, err))
+				}
+				continue
+			}
+
+			publicKeys := NewPublicKeys(keySet)
+
+			k.lock.Lock()
+			k.keySet = keySet
+			k.publicKeys = publicKeys
+			k.lock.Unlock()
+		}
+	}
+}
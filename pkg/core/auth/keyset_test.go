@@ -0,0 +1,189 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/core/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRefreshingKeySet(t *testing.T) {
+	oldPrivateKey, oldKeySet := newTestKey(t)
+	newPrivateKey, newKeySet := newTestKey(t)
+
+	var lock sync.Mutex
+	servedKeySet := oldKeySet
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			json.NewEncoder(w).Encode(servedKeySet)
+		},
+	))
+	defer server.Close()
+
+	keySet, err := auth.NewRefreshingKeySet(server.URL, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer keySet.Close()
+
+	service := auth.NewService(
+		auth.Conf{},
+		auth.ServiceWithKeySetProvider(keySet),
+	)
+
+	claims := auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+	oldToken := signTestToken(t, oldPrivateKey, claims)
+	newToken := signTestToken(t, newPrivateKey, claims)
+
+	if err := service.ValidateToken(oldToken); err != nil {
+		t.Errorf("expected token signed with the old key to be valid, got '%v'", err)
+	}
+	if err := service.ValidateToken(newToken); err == nil {
+		t.Errorf("expected token signed with the new key to be invalid before the rotation")
+	}
+
+	// Rotate the keys served by the identity provider
+	lock.Lock()
+	servedKeySet = newKeySet
+	lock.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+	for service.ValidateToken(newToken) != nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := service.ValidateToken(newToken); err != nil {
+		t.Errorf("expected token signed with the new key to be valid after the rotation, got '%v'", err)
+	}
+	if err := service.ValidateToken(oldToken); err == nil {
+		t.Errorf("expected token signed with the old key to be invalid after the rotation")
+	}
+}
+
+type countingTransport struct {
+	requests atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestRefreshingKeySetOptions(t *testing.T) {
+	_, keySet := newTestKey(t)
+
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if failing.Load() {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"error":"temporarily_unavailable"}`))
+				return
+			}
+
+			json.NewEncoder(w).Encode(keySet)
+		},
+	))
+	defer server.Close()
+
+	transport := &countingTransport{}
+	refreshErrs := make(chan error, 1)
+	refreshingKeySet, err := auth.NewRefreshingKeySet(
+		server.URL,
+		10*time.Millisecond,
+		auth.KeySetWithHTTPClient(&http.Client{Transport: transport}),
+		auth.KeySetWithRefreshErrorHandler(func(err error) {
+			select {
+			case refreshErrs <- err:
+			default:
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer refreshingKeySet.Close()
+
+	if count := transport.requests.Load(); count != 1 {
+		t.Errorf("expected the key set to be fetched with the given client, got %d requests", count)
+	}
+
+	failing.Store(true)
+
+	select {
+	case err := <-refreshErrs:
+		if !errors.Is(err, auth.ErrKeySetRefreshFailed) || !errors.Is(err, auth.ErrKeySetRequestFailed) {
+			t.Errorf("expected error to be '%v', got '%v'", auth.ErrKeySetRequestFailed, err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected the refresh failure to be reported")
+	}
+
+	if len(refreshingKeySet.KeySet().Keys) != 1 {
+		t.Errorf("expected the current key set to be kept after a failed refresh")
+	}
+}
+
+func TestRefreshingKeySetWithoutKeys(t *testing.T) {
+	_, keySet := newTestKey(t)
+
+	var empty atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if empty.Load() {
+				json.NewEncoder(w).Encode(auth.KeySet{})
+				return
+			}
+
+			json.NewEncoder(w).Encode(keySet)
+		},
+	))
+	defer server.Close()
+
+	refreshErrs := make(chan error, 1)
+	refreshingKeySet, err := auth.NewRefreshingKeySet(
+		server.URL,
+		10*time.Millisecond,
+		auth.KeySetWithRefreshErrorHandler(func(err error) {
+			select {
+			case refreshErrs <- err:
+			default:
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer refreshingKeySet.Close()
+
+	empty.Store(true)
+
+	select {
+	case err := <-refreshErrs:
+		if !errors.Is(err, auth.ErrInvalidKeySet) {
+			t.Errorf("expected error to be '%v', got '%v'", auth.ErrInvalidKeySet, err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected the refresh without keys to be reported")
+	}
+
+	if len(refreshingKeySet.KeySet().Keys) != 1 {
+		t.Errorf("expected the key set without keys not to replace the current one")
+	}
+}
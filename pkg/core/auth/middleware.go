@@ -2,7 +2,15 @@ package auth
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+)
+
+var (
+	errMissingHeader       = errors.New("missing authorization header")
+	errUserInfoUnavailable = errors.New("user information unavailable")
 )
 
 // Middleware for JWT based user authentication
@@ -12,20 +20,13 @@ func Middleware(
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(
 			func(w http.ResponseWriter, r *http.Request) {
-				header := r.Header.Get("Authorization")
-				if header == "" {
-					http.Error(w, "Missing JWT token", http.StatusUnauthorized)
-					return
-				}
-
-				token, err := TokenFromHeader(header)
+				r, err := authenticateRequest(service, r)
 				if err != nil {
-					http.Error(w, "Invalid JWT token", http.StatusUnauthorized)
-					return
-				}
-
-				if err = service.ValidateToken(token); err != nil {
-					if errors.Is(err, ErrTokenExpired) {
+					if errors.Is(err, errMissingHeader) {
+						http.Error(w, "Missing JWT token", http.StatusUnauthorized)
+					} else if errors.Is(err, errUserInfoUnavailable) {
+						http.Error(w, "Internal server error", http.StatusInternalServerError)
+					} else if errors.Is(err, ErrTokenExpired) {
 						http.Error(w, "Expired JWT token", http.StatusUnauthorized)
 					} else if errors.Is(err, ErrTokenNotValidYet) {
 						http.Error(w, "JWT token is not valid yet", http.StatusUnauthorized)
@@ -35,27 +36,119 @@ func Middleware(
 					return
 				}
 
-				// Add the access token to the request's context
-				r = RequestWithToken(r, token)
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
 
-				// Add the token claims to the request's context
-				claims, err := service.TokenClaims(token)
+// Middleware for optional JWT based user authentication, requests with a valid
+// token get the same context as with the authentication middleware while the
+// requests without a token or with an invalid one proceed anonymously
+func OptionalMiddleware(
+	service *Service,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				authenticatedRequest, err := authenticateRequest(service, r)
 				if err != nil {
-					http.Error(w, "Invalid JWT token", http.StatusUnauthorized)
+					if errors.Is(err, errUserInfoUnavailable) {
+						http.Error(w, "Internal server error", http.StatusInternalServerError)
+						return
+					}
+
+					next.ServeHTTP(w, r)
 					return
 				}
-				r = RequestWithTokenClaims(r, claims)
 
-				// Add the user information to the request's context
-				userInfo, err := service.UserInfo(token)
-				if err != nil {
-					http.Error(w, "Internal server error", http.StatusInternalServerError)
+				next.ServeHTTP(w, authenticatedRequest)
+			},
+		)
+	}
+}
+
+// Returns a shallow copy of the request with the token, its claims and the user
+// information in its context
+func authenticateRequest(
+	service *Service,
+	r *http.Request,
+) (*http.Request, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, errMissingHeader
+	}
+
+	token, err := TokenFromHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := service.ValidateTokenClaimsContext(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the access token to the request's context
+	r = RequestWithToken(r, token)
+
+	// Add the token claims to the request's context
+	r = RequestWithTokenClaims(r, claims)
+
+	// Add the user information to the request's context
+	userInfo, err := service.userInfoFromClaims(r.Context(), token, claims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errUserInfoUnavailable, err)
+	}
+	r = RequestWithUserInfo(r, *userInfo)
+
+	return r, nil
+}
+
+// Middleware for scope based user authorization, it must be used after the
+// authentication middleware which sets the token claims in the request's context
+func RequireScopes(
+	scopes ...string,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				claims, found := TokenClaimsFromRequest(r)
+				if !found {
+					http.Error(w, "Missing JWT token", http.StatusUnauthorized)
 					return
 				}
-				r = RequestWithUserInfo(r, *userInfo)
+
+				tokenScopes := TokenScopes(claims)
+				for _, scope := range scopes {
+					if !slices.Contains(tokenScopes, scope) {
+						http.Error(w, "Insufficient scope", http.StatusForbidden)
+						return
+					}
+				}
 
 				next.ServeHTTP(w, r)
 			},
 		)
 	}
 }
+
+// Extracts the scopes granted by the given token claims, either from the
+// space-delimited "scope" claim or from the "scp" and "permissions" lists
+func TokenScopes(claims MapClaims) []string {
+	var scopes []string
+	for _, key := range []string{"scope", "scp", "permissions"} {
+		switch value := claims[key].(type) {
+		case string:
+			scopes = append(scopes, strings.Fields(value)...)
+		case []any:
+			for _, item := range value {
+				if scope, valid := item.(string); valid {
+					scopes = append(scopes, scope)
+				}
+			}
+		}
+	}
+
+	return scopes
+}
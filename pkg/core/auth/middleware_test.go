@@ -0,0 +1,111 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/core/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRequireScopes(t *testing.T) {
+	handler := auth.RequireScopes("read:logs", "write:logs")(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		},
+	))
+
+	tests := []struct {
+		name   string
+		claims auth.MapClaims
+		status int
+	}{
+		{"scope claim", auth.MapClaims{"scope": "openid read:logs write:logs"}, http.StatusOK},
+		{"permissions claim", auth.MapClaims{"permissions": []any{"read:logs", "write:logs"}}, http.StatusOK},
+		{"missing scope", auth.MapClaims{"scope": "openid read:logs"}, http.StatusForbidden},
+		{"no scopes", auth.MapClaims{"sub": "user_1"}, http.StatusForbidden},
+		{"no claims", nil, http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.claims != nil {
+				req = auth.RequestWithTokenClaims(req, test.claims)
+			}
+
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+
+			if res.Code != test.status {
+				t.Errorf("expected status to be %d, got %d", test.status, res.Code)
+			}
+		})
+	}
+}
+
+func TestOptionalMiddleware(t *testing.T) {
+	privateKey, keySet := newTestKey(t)
+	service := auth.NewService(
+		auth.Conf{
+			KeySet:    keySet,
+			DomainURL: "https://idp.test",
+		},
+		auth.ServiceWithHTTPClient(&http.Client{
+			Transport: &recordingTransport{
+				responses: map[string]string{
+					"https://idp.test/userinfo": `{"sub":"user_1"}`,
+				},
+			},
+		}),
+	)
+
+	handler := auth.OptionalMiddleware(service)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			userInfo, found := auth.UserInfoFromRequest(r)
+			if !found {
+				w.Write([]byte("anonymous"))
+				return
+			}
+
+			w.Write([]byte(userInfo.ID))
+		},
+	))
+
+	validToken := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		body   string
+	}{
+		{"authenticated", "Bearer " + validToken, "user_1"},
+		{"anonymous", "", "anonymous"},
+		{"invalid token", "Bearer invalid", "anonymous"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.header != "" {
+				req.Header.Set("Authorization", test.header)
+			}
+
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+
+			if res.Code != http.StatusOK {
+				t.Errorf("expected status to be %d, got %d", http.StatusOK, res.Code)
+			}
+			if body := res.Body.String(); body != test.body {
+				t.Errorf("expected body to be '%s', got '%s'", test.body, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/cache"
+	"github.com/sergioneiravargas/template-go/pkg/framework/log"
+
+	"go.uber.org/fx"
+)
+
+// Configuration of the auth module
+type ModuleConf struct {
+	Conf      Conf
+	KeySetURL string
+	// HTTP client used to reach the identity provider (e.g. through a proxy or
+	// with mTLS), defaults to a client with the configured HTTP timeout
+	HTTPClient *http.Client
+}
+
+// Module providing the auth service from a ModuleConf
+var Module = fx.Module(
+	"auth",
+	fx.Provide(
+		NewModuleService,
+	),
+)
+
+// Creates the auth service with a user info cache and a key set refreshed
+// hourly, both stopped with the application. The key set refresh failures are
+// logged
+func NewModuleService(
+	lc fx.Lifecycle,
+	conf ModuleConf,
+	logger *log.Logger,
+) (*Service, error) {
+	httpClient := conf.HTTPClient
+	if httpClient == nil {
+		httpClient = confHTTPClient(conf.Conf)
+	}
+
+	userInfoCache := cache.New[string, *UserInfo](
+		cache.WithTTL[string, *UserInfo](10*time.Minute),
+		cache.WithCleanupInterval[string, *UserInfo](30*time.Second),
+	)
+	lc.Append(fx.StopHook(userInfoCache.Close))
+
+	keySet, err := NewRefreshingKeySet(
+		conf.KeySetURL,
+		time.Hour,
+		KeySetWithHTTPClient(httpClient),
+		KeySetWithRefreshErrorHandler(func(err error) {
+			logger.Error("Key set refresh failed", struct {
+				Error string `json:"error"`
+			}{
+				Error: err.Error(),
+			})
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	lc.Append(fx.StopHook(keySet.Close))
+
+	return NewService(
+		conf.Conf,
+		ServiceWithHTTPClient(httpClient),
+		ServiceWithUserInfoCache(userInfoCache),
+		ServiceWithKeySetProvider(keySet),
+	), nil
+}
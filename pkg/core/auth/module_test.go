@@ -0,0 +1,22 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/sergioneiravargas/template-go/pkg/core/auth"
+	"github.com/sergioneiravargas/template-go/pkg/framework/log"
+
+	"go.uber.org/fx"
+)
+
+func TestModule(t *testing.T) {
+	err := fx.ValidateApp(
+		fx.Supply(auth.ModuleConf{}),
+		fx.Supply(log.NewNopLogger()),
+		auth.Module,
+		fx.Invoke(func(*auth.Service) {}),
+	)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
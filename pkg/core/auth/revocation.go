@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/cache"
+)
+
+var (
+	ErrTokenRevoked   = errors.New("token is revoked")
+	ErrMissingTokenID = errors.New("token has no ID")
+	ErrNoRevocations  = errors.New("no revocation store configured")
+)
+
+// Store of the revoked token IDs ("jti" claim)
+type RevocationStore interface {
+	IsRevoked(jti string) bool
+	// Revokes the token ID until the given time, a zero time revokes it forever
+	Revoke(jti string, until time.Time)
+}
+
+// Revocation store keeping the revoked token IDs in memory until the tokens expire
+type MemoryRevocationStore struct {
+	revokedIDs *cache.Cache[string, struct{}]
+}
+
+// Creates a new in-memory revocation store, it must be closed once it's no
+// longer needed
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		revokedIDs: cache.New[string, struct{}](
+			cache.WithCleanupInterval[string, struct{}](time.Minute),
+		),
+	}
+}
+
+func (s *MemoryRevocationStore) IsRevoked(jti string) bool {
+	_, found := s.revokedIDs.Get(jti)
+
+	return found
+}
+
+func (s *MemoryRevocationStore) Revoke(jti string, until time.Time) {
+	if until.IsZero() {
+		s.revokedIDs.Set(jti, struct{}{})
+		return
+	}
+
+	// Tokens that already expired don't need to be kept
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return
+	}
+
+	s.revokedIDs.SetWithTTL(jti, struct{}{}, ttl)
+}
+
+// Stops the cleanup of the expired token IDs
+func (s *MemoryRevocationStore) Close() {
+	s.revokedIDs.Close()
+}
@@ -0,0 +1,84 @@
+package auth_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/core/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestServiceRevoke(t *testing.T) {
+	privateKey, keySet := newTestKey(t)
+	store := auth.NewMemoryRevocationStore()
+	defer store.Close()
+
+	service := auth.NewService(
+		auth.Conf{KeySet: keySet},
+		auth.ServiceWithRevocationStore(store),
+	)
+
+	token := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"jti": "token_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	otherToken := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"jti": "token_2",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+
+	if err := service.ValidateToken(token); err != nil {
+		t.Fatalf("expected token to be valid before the revocation, got '%v'", err)
+	}
+
+	if err := service.Revoke(token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := service.ValidateToken(token); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrTokenRevoked, err)
+	}
+	if _, err := service.TokenClaims(token); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrTokenRevoked, err)
+	}
+	if _, err := service.ValidateTokenDetailed(token); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrTokenRevoked, err)
+	}
+
+	if err := service.ValidateToken(otherToken); err != nil {
+		t.Errorf("expected other token to remain valid, got '%v'", err)
+	}
+
+	tokenWithoutID := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	if err := service.Revoke(tokenWithoutID); !errors.Is(err, auth.ErrMissingTokenID) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrMissingTokenID, err)
+	}
+}
+
+func TestMemoryRevocationStoreExpiry(t *testing.T) {
+	store := auth.NewMemoryRevocationStore()
+	defer store.Close()
+
+	store.Revoke("token_1", time.Now().Add(20*time.Millisecond))
+	store.Revoke("token_2", time.Time{})
+
+	if !store.IsRevoked("token_1") || !store.IsRevoked("token_2") {
+		t.Fatalf("expected tokens to be revoked")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if store.IsRevoked("token_1") {
+		t.Errorf("expected revocation to be dropped once the token expired")
+	}
+	if !store.IsRevoked("token_2") {
+		t.Errorf("expected revocation without expiry to be kept")
+	}
+}
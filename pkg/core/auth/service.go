@@ -1,22 +1,90 @@
 package auth
 
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
 type UserInfoCache interface {
 	Get(key string) (value *UserInfo, found bool)
 	Set(key string, value *UserInfo)
 	Unset(key string)
 }
 
+// Key set provider that also keeps the decoded public keys
+type publicKeysProvider interface {
+	PublicKeys() *PublicKeys
+}
+
+// Time limit of a user info fetch shared by concurrent callers
+const sharedFetchTimeout = 10 * time.Second
+
+// User info cache able to load the missing values only once
+type userInfoLoader interface {
+	GetOrSet(key string, load func() (*UserInfo, error)) (*UserInfo, error)
+}
+
 // Service for auth operations
 type Service struct {
-	keySet        KeySet
-	domainURL     string
-	userInfoCache UserInfoCache
+	publicKeys     *PublicKeys
+	secret         []byte
+	keySetProvider KeySetProvider
+	domainURL      string
+	userInfoCache  UserInfoCache
+	httpClient     *http.Client
+	revocations    RevocationStore
+
+	expectedIssuer   string
+	expectedAudience []string
+	leeway           time.Duration
+	rolesClaim       string
+
+	validationStrategy ValidationStrategy
+	introspectionURL   string
+	tokenURL           string
+	clientID           string
+	clientSecret       string
+
+	notValidYetRetryWindow time.Duration
 }
 
 // Auth service configuration
 type Conf struct {
 	KeySet    KeySet
 	DomainURL string
+
+	// Shared secret to validate HS256 signed tokens, e.g. internal service tokens
+	Secret []byte
+
+	// Issuer the tokens must be issued by, not checked when empty
+	ExpectedIssuer string
+	// Audiences the tokens must be intended for (any of them), not checked when empty
+	ExpectedAudience []string
+	// Clock skew tolerated when validating the token time based claims
+	Leeway time.Duration
+	// Dot separated path of the claim holding the user roles (e.g. "groups" or
+	// "realm_access.roles"), the roles are not populated when empty
+	RolesClaim string
+
+	// Strategy to validate the tokens with, JWT validation by default
+	ValidationStrategy ValidationStrategy
+	// Token introspection endpoint, required by the introspection strategies
+	IntrospectionURL string
+	// Token endpoint, defaults to the domain's "/oauth/token"
+	TokenURL string
+	// Client credentials to authenticate with the identity provider
+	ClientID     string
+	ClientSecret string
+
+	// Timeout of the requests to the identity provider, ignored when an HTTP
+	// client is given with ServiceWithHTTPClient
+	HTTPTimeout time.Duration
 }
 
 // Service option
@@ -29,16 +97,76 @@ func ServiceWithUserInfoCache(cache UserInfoCache) ServiceOption {
 	}
 }
 
+// Service option to take the key set from the given provider instead of the
+// configured one, e.g. a RefreshingKeySet
+func ServiceWithKeySetProvider(provider KeySetProvider) ServiceOption {
+	return func(s *Service) {
+		s.keySetProvider = provider
+	}
+}
+
+// Service option to set the store of the revoked tokens
+func ServiceWithRevocationStore(store RevocationStore) ServiceOption {
+	return func(s *Service) {
+		s.revocations = store
+	}
+}
+
+// Service option to set the HTTP client used to reach the identity provider
+func ServiceWithHTTPClient(httpClient *http.Client) ServiceOption {
+	return func(s *Service) {
+		s.httpClient = httpClient
+	}
+}
+
+// Service option to revalidate once the tokens that are not valid yet but become
+// valid within the given window, useful when tokens are issued and validated
+// almost at the same instant
+func ServiceWithNotValidYetRetry(window time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.notValidYetRetryWindow = window
+	}
+}
+
+// Returns the HTTP client to reach the identity provider with the configured timeout
+func confHTTPClient(conf Conf) *http.Client {
+	if conf.HTTPTimeout > 0 {
+		return &http.Client{
+			Timeout: conf.HTTPTimeout,
+		}
+	}
+
+	return defaultHTTPClient
+}
+
 // Creates a new auth service
 func NewService(
 	conf Conf,
 	opts ...ServiceOption,
 ) *Service {
 	service := Service{
-		keySet:    conf.KeySet,
-		domainURL: conf.DomainURL,
+		publicKeys: NewPublicKeys(conf.KeySet),
+		secret:     conf.Secret,
+		domainURL:  conf.DomainURL,
+
+		expectedIssuer:   conf.ExpectedIssuer,
+		expectedAudience: conf.ExpectedAudience,
+		leeway:           conf.Leeway,
+		rolesClaim:       conf.RolesClaim,
+
+		validationStrategy: conf.ValidationStrategy,
+		introspectionURL:   conf.IntrospectionURL,
+		tokenURL:           conf.TokenURL,
+		clientID:           conf.ClientID,
+		clientSecret:       conf.ClientSecret,
+	}
+
+	if service.tokenURL == "" {
+		service.tokenURL = conf.DomainURL + "/oauth/token"
 	}
 
+	service.httpClient = confHTTPClient(conf)
+
 	for _, opt := range opts {
 		opt(&service)
 	}
@@ -46,31 +174,136 @@ func NewService(
 	return &service
 }
 
-// Validates the given token
+// Validates the given token according to the validation strategy
 func (s *Service) ValidateToken(token string) error {
-	parsedToken, err := ParseToken(token, s.keySet)
+	_, err := s.ValidateTokenClaimsContext(context.Background(), token)
+
+	return err
+}
+
+// Validates the given token according to the validation strategy returning its
+// claims, the token is introspected at most once and the introspection request
+// is aborted when the context is done
+func (s *Service) ValidateTokenClaimsContext(
+	ctx context.Context,
+	token string,
+) (MapClaims, error) {
+	switch s.validationStrategy {
+	case ValidationStrategyIntrospection:
+		return s.introspectToken(ctx, token)
+	case ValidationStrategyJWTWithIntrospectionFallback:
+		claims, err := s.validateJWT(ctx, token)
+		if errors.Is(err, ErrTokenMalformed) {
+			return s.introspectToken(ctx, token)
+		}
+
+		return claims, err
+	default:
+		return s.validateJWT(ctx, token)
+	}
+}
+
+func (s *Service) validateJWT(ctx context.Context, token string) (MapClaims, error) {
+	claims, err := s.jwtClaims(token)
+	if !errors.Is(err, ErrTokenNotValidYet) || s.notValidYetRetryWindow <= 0 {
+		return claims, err
+	}
+
+	// Wait until the token becomes valid if it's within the retry window, the
+	// leeway already accepts it that much earlier
+	delay, found := notValidYetDelay(token)
+	if !found {
+		return nil, err
+	}
+	delay = max(delay-s.leeway, 0)
+	if delay > s.notValidYetRetryWindow {
+		return nil, err
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+	}
+
+	return s.jwtClaims(token)
+}
+
+// Detailed outcome of a token validation
+type ValidationResult struct {
+	// Whether the token was parsed and its signature verified
+	SignatureValid bool
+	// Whether the token expiration time has passed
+	Expired bool
+	// The token claims, set whenever the signature is valid
+	Claims MapClaims
+}
+
+// Validates the given token telling apart the tokens that are expired but
+// otherwise valid, which are reported along with ErrTokenExpired. Only JWTs
+// are supported, the token is never introspected regardless of the validation
+// strategy
+func (s *Service) ValidateTokenDetailed(token string) (ValidationResult, error) {
+	parsedToken, err := s.parseSignedToken(token, jwt.WithoutClaimsValidation())
 	if err != nil {
-		return err
+		return ValidationResult{}, err
 	}
 
-	if !parsedToken.Valid {
-		return ErrInvalidToken
+	claims, valid := parsedToken.Claims.(MapClaims)
+	if !valid {
+		return ValidationResult{SignatureValid: true}, ErrInvalidTokenClaims
 	}
 
-	return nil
+	result := ValidationResult{
+		SignatureValid: true,
+		Claims:         claims,
+	}
+
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil {
+		return result, ErrInvalidTokenClaims
+	}
+
+	// Validate the other claims apart from the expiration, as the validator
+	// joins every failure and an expired token must be otherwise valid
+	otherClaims := maps.Clone(claims)
+	delete(otherClaims, "exp")
+	if err := jwt.NewValidator(s.parserOptions()...).Validate(otherClaims); err != nil {
+		return result, tokenError(err)
+	}
+
+	if err := s.validateAudience(claims); err != nil {
+		return result, err
+	}
+
+	if err := s.validateRevocation(claims); err != nil {
+		return result, err
+	}
+
+	if expiresAt != nil && time.Now().Add(-s.leeway).After(expiresAt.Time) {
+		result.Expired = true
+
+		return result, ErrTokenExpired
+	}
+
+	return result, nil
 }
 
-// Retrieves the claims from the given token
+// Retrieves the claims from the given token, for introspected tokens these are
+// the introspection response fields
 func (s *Service) TokenClaims(token string) (MapClaims, error) {
-	parsedToken, err := ParseToken(token, s.keySet)
+	return s.ValidateTokenClaimsContext(context.Background(), token)
+}
+
+func (s *Service) jwtClaims(token string) (MapClaims, error) {
+	parsedToken, err := s.parseToken(token)
 	if err != nil {
 		return nil, err
 	}
 
-	if !parsedToken.Valid {
-		return nil, ErrInvalidToken
-	}
-
 	claims, valid := parsedToken.Claims.(MapClaims)
 	if !valid {
 		return nil, ErrInvalidTokenClaims
@@ -79,21 +312,108 @@ func (s *Service) TokenClaims(token string) (MapClaims, error) {
 	return claims, nil
 }
 
+// Revokes the given token so it's no longer valid, it requires a revocation
+// store and the token to have an ID ("jti" claim)
+func (s *Service) Revoke(token string) error {
+	if s.revocations == nil {
+		return ErrNoRevocations
+	}
+
+	claims, err := s.jwtClaims(token)
+	if err != nil {
+		return err
+	}
+
+	jti, valid := claims["jti"].(string)
+	if !valid || jti == "" {
+		return ErrMissingTokenID
+	}
+
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil {
+		return ErrInvalidTokenClaims
+	}
+
+	// Keep the token revoked for as long as it could still be accepted
+	var until time.Time
+	if expiresAt != nil {
+		until = expiresAt.Add(s.leeway)
+	}
+	s.revocations.Revoke(jti, until)
+
+	return nil
+}
+
+// Requests new tokens with the given refresh token using the client credentials
+func (s *Service) RefreshToken(refreshToken string) (TokenResponse, error) {
+	return RefreshTokenWithClient(
+		s.httpClient,
+		s.tokenURL,
+		s.clientID,
+		s.clientSecret,
+		refreshToken,
+	)
+}
+
 // Retrieves the user information from the given access token
 func (s *Service) UserInfo(
 	token string,
 ) (*UserInfo, error) {
-	// Check if the user information is in cache
-	claims, err := s.TokenClaims(token)
+	return s.UserInfoContext(context.Background(), token)
+}
+
+// Retrieves the user information from the given access token, the request to
+// the identity provider is aborted when the context is done
+func (s *Service) UserInfoContext(
+	ctx context.Context,
+	token string,
+) (*UserInfo, error) {
+	claims, err := s.ValidateTokenClaimsContext(ctx, token)
 	if err != nil {
 		return nil, err
 	}
 
+	return s.userInfoFromClaims(ctx, token, claims)
+}
+
+// Retrieves the user information for the given access token once its claims
+// are known, so they're not parsed or introspected again
+func (s *Service) userInfoFromClaims(
+	ctx context.Context,
+	token string,
+	claims MapClaims,
+) (*UserInfo, error) {
 	userID, valid := claims["sub"].(string)
 	if !valid {
 		return nil, ErrInvalidTokenClaims
 	}
 
+	userInfo, err := s.userInfo(ctx, userID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.rolesClaim == "" {
+		return userInfo, nil
+	}
+
+	// The roles come from the token, so they're set on a copy of the cached value
+	userInfoWithRoles := *userInfo
+	userInfoWithRoles.Roles = RolesFromClaims(claims, s.rolesClaim)
+
+	return &userInfoWithRoles, nil
+}
+
+func (s *Service) userInfo(
+	ctx context.Context,
+	userID string,
+	token string,
+) (*UserInfo, error) {
+	// Deduplicate concurrent fetches for the same user when the cache supports it
+	if loader, valid := s.userInfoCache.(userInfoLoader); valid {
+		return s.sharedUserInfo(ctx, loader, userID, token)
+	}
+
 	if s.userInfoCache != nil {
 		//  Check if the user information is in cache and return it if found
 		userInfo, found := s.userInfoCache.Get(userID)
@@ -103,7 +423,7 @@ func (s *Service) UserInfo(
 	}
 
 	// Fetch the user information
-	userInfo, err := FetchUserInfo(s.domainURL+"/userinfo", token)
+	userInfo, err := FetchUserInfoContext(ctx, s.httpClient, s.domainURL+"/userinfo", token)
 	if err != nil {
 		return nil, err
 	}
@@ -115,3 +435,176 @@ func (s *Service) UserInfo(
 
 	return userInfo, nil
 }
+
+// Fetches the user information once for the concurrent callers. The shared
+// fetch isn't bound to the context of the caller that started it, so a caller
+// going away doesn't fail the others, and each caller stops waiting when its
+// own context is done
+func (s *Service) sharedUserInfo(
+	ctx context.Context,
+	loader userInfoLoader,
+	userID string,
+	token string,
+) (*UserInfo, error) {
+	type result struct {
+		userInfo *UserInfo
+		err      error
+	}
+
+	results := make(chan result, 1)
+	go func() {
+		userInfo, err := loader.GetOrSet(userID, func() (*UserInfo, error) {
+			fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedFetchTimeout)
+			defer cancel()
+
+			return FetchUserInfoContext(fetchCtx, s.httpClient, s.domainURL+"/userinfo", token)
+		})
+		results <- result{userInfo, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-results:
+		return result.userInfo, result.err
+	}
+}
+
+// Parses the given token validating the claims expected by the service
+func (s *Service) parseToken(token string) (*Token, error) {
+	parsedToken, err := s.parseSignedToken(token, s.parserOptions()...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !parsedToken.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	if err := s.validateAudience(parsedToken.Claims); err != nil {
+		return nil, err
+	}
+
+	if claims, valid := parsedToken.Claims.(MapClaims); valid {
+		if err := s.validateRevocation(claims); err != nil {
+			return nil, err
+		}
+	}
+
+	return parsedToken, nil
+}
+
+// Parses the given token with the shared secret for HS256 tokens when one is
+// configured, or with the key set otherwise
+func (s *Service) parseSignedToken(token string, opts ...ParserOption) (*Token, error) {
+	if len(s.secret) > 0 && tokenAlgorithm(token) == jwt.SigningMethodHS256.Alg() {
+		return ParseTokenWithSecret(token, s.secret, opts...)
+	}
+
+	return ParseTokenWithPublicKeys(token, s.currentPublicKeys(), opts...)
+}
+
+func (s *Service) parserOptions() []ParserOption {
+	var opts []ParserOption
+	if s.expectedIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(s.expectedIssuer))
+	}
+	if s.leeway > 0 {
+		opts = append(opts, jwt.WithLeeway(s.leeway))
+	}
+
+	return opts
+}
+
+// Checks that the token is intended for any of the expected audiences, as the
+// JWT library only supports checking a single one
+func (s *Service) validateAudience(claims jwt.Claims) error {
+	if len(s.expectedAudience) == 0 {
+		return nil
+	}
+
+	audience, err := claims.GetAudience()
+	if err != nil {
+		return ErrInvalidAudience
+	}
+
+	for _, aud := range audience {
+		if slices.Contains(s.expectedAudience, aud) {
+			return nil
+		}
+	}
+
+	return ErrInvalidAudience
+}
+
+// Introspects the given token validating the response claims the same way as
+// the claims of a JWT
+func (s *Service) introspectToken(ctx context.Context, token string) (MapClaims, error) {
+	claims, err := IntrospectTokenContext(
+		ctx,
+		s.httpClient,
+		s.introspectionURL,
+		s.clientID,
+		s.clientSecret,
+		token,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := jwt.NewValidator(s.parserOptions()...).Validate(claims); err != nil {
+		return nil, tokenError(err)
+	}
+
+	if err := s.validateAudience(claims); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateRevocation(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// Checks that the token wasn't revoked, the tokens without ID can't be revoked
+func (s *Service) validateRevocation(claims MapClaims) error {
+	if s.revocations == nil {
+		return nil
+	}
+
+	jti, valid := claims["jti"].(string)
+	if valid && s.revocations.IsRevoked(jti) {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// Returns the public keys to validate the tokens with
+func (s *Service) currentPublicKeys() *PublicKeys {
+	if provider, valid := s.keySetProvider.(publicKeysProvider); valid {
+		return provider.PublicKeys()
+	}
+
+	if s.keySetProvider != nil {
+		return NewPublicKeys(s.keySetProvider.KeySet())
+	}
+
+	return s.publicKeys
+}
+
+// Returns the time remaining until the given token becomes valid
+func notValidYetDelay(token string) (time.Duration, bool) {
+	var claims MapClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil {
+		return 0, false
+	}
+
+	notBefore, err := claims.GetNotBefore()
+	if err != nil || notBefore == nil {
+		return 0, false
+	}
+
+	return max(time.Until(notBefore.Time), 0), true
+}
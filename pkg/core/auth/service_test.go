@@ -0,0 +1,559 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/core/auth"
+	"github.com/sergioneiravargas/template-go/pkg/framework/cache"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testKeyID = "test_key"
+
+func newTestKey(t testing.TB) (*rsa.PrivateKey, auth.KeySet) {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error generating RSA key: %v", err)
+	}
+
+	keySet := auth.KeySet{
+		Keys: []auth.Key{
+			{
+				Kid: testKeyID,
+				Alg: "RS256",
+				Kty: "RSA",
+				N:   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+				Use: "sig",
+			},
+		},
+	}
+
+	return privateKey, keySet
+}
+
+func signTestToken(t testing.TB, privateKey *rsa.PrivateKey, claims auth.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKeyID
+
+	signedToken, err := token.SignedString(privateKey)
+	if err != nil {
+		t.Fatalf("unexpected error signing token: %v", err)
+	}
+
+	return signedToken
+}
+
+func TestServiceNotValidYetRetry(t *testing.T) {
+	// Sub-second "nbf" values are truncated with the default precision
+	precision := jwt.TimePrecision
+	jwt.TimePrecision = time.Millisecond
+	defer func() { jwt.TimePrecision = precision }()
+
+	privateKey, keySet := newTestKey(t)
+	now := time.Now()
+	token := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"nbf": jwt.NewNumericDate(now.Add(50 * time.Millisecond)),
+		"exp": jwt.NewNumericDate(now.Add(time.Hour)),
+	})
+
+	strictService := auth.NewService(auth.Conf{KeySet: keySet})
+	if err := strictService.ValidateToken(token); !errors.Is(err, auth.ErrTokenNotValidYet) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrTokenNotValidYet, err)
+	}
+
+	retryService := auth.NewService(
+		auth.Conf{KeySet: keySet},
+		auth.ServiceWithNotValidYetRetry(time.Second),
+	)
+	if err := retryService.ValidateToken(token); err != nil {
+		t.Errorf("expected token to be valid after retry, got '%v'", err)
+	}
+
+	// Tokens outside of the retry window are rejected without waiting
+	farToken := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"nbf": jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		"exp": jwt.NewNumericDate(time.Now().Add(2 * time.Hour)),
+	})
+	if err := retryService.ValidateToken(farToken); !errors.Is(err, auth.ErrTokenNotValidYet) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrTokenNotValidYet, err)
+	}
+}
+
+func TestServiceNotValidYetRetryContext(t *testing.T) {
+	privateKey, keySet := newTestKey(t)
+	token := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"nbf": jwt.NewNumericDate(time.Now().Add(5 * time.Second)),
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+
+	service := auth.NewService(
+		auth.Conf{KeySet: keySet},
+		auth.ServiceWithNotValidYetRetry(10*time.Second),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := service.ValidateTokenClaimsContext(ctx, token); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error to be '%v', got '%v'", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the retry to stop with the context, took %s", elapsed)
+	}
+
+	// The leeway shortens the wait, accepting the token before its "nbf"
+	leewayService := auth.NewService(
+		auth.Conf{KeySet: keySet, Leeway: 4 * time.Second},
+		auth.ServiceWithNotValidYetRetry(2*time.Second),
+	)
+	if err := leewayService.ValidateToken(token); err != nil {
+		t.Errorf("expected token to be valid after retry, got '%v'", err)
+	}
+}
+
+func TestServiceValidateTokenDetailed(t *testing.T) {
+	privateKey, keySet := newTestKey(t)
+	service := auth.NewService(auth.Conf{KeySet: keySet})
+
+	expiredToken := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	})
+
+	result, err := service.ValidateTokenDetailed(expiredToken)
+	if !errors.Is(err, auth.ErrTokenExpired) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrTokenExpired, err)
+	}
+	if !result.SignatureValid {
+		t.Errorf("expected signature of the expired token to be valid")
+	}
+	if !result.Expired {
+		t.Errorf("expected token to be reported as expired")
+	}
+	if result.Claims["sub"] != "user_1" {
+		t.Errorf("expected claims of the expired token to be available")
+	}
+
+	// Tamper the payload while keeping the original signature
+	otherToken := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_2",
+		"exp": jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	})
+	expiredParts := strings.Split(expiredToken, ".")
+	otherParts := strings.Split(otherToken, ".")
+	tamperedToken := strings.Join([]string{expiredParts[0], otherParts[1], expiredParts[2]}, ".")
+
+	result, err = service.ValidateTokenDetailed(tamperedToken)
+	if err == nil {
+		t.Errorf("expected tampered token to be rejected")
+	}
+	if result.SignatureValid {
+		t.Errorf("expected signature of the tampered token to be invalid")
+	}
+	if result.Expired {
+		t.Errorf("expected tampered token not to be reported as expired")
+	}
+
+	validToken := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+
+	result, err = service.ValidateTokenDetailed(validToken)
+	if err != nil {
+		t.Errorf("expected token to be valid, got '%v'", err)
+	}
+	if !result.SignatureValid || result.Expired {
+		t.Errorf("expected valid signature and unexpired token, got %+v", result)
+	}
+}
+
+func TestServiceValidateTokenDetailedOtherFailures(t *testing.T) {
+	privateKey, keySet := newTestKey(t)
+	store := auth.NewMemoryRevocationStore()
+	defer store.Close()
+	service := auth.NewService(
+		auth.Conf{KeySet: keySet, ExpectedIssuer: "https://issuer.example.com"},
+		auth.ServiceWithRevocationStore(store),
+	)
+
+	foreignToken := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"iss": "https://other.example.com",
+		"exp": jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	})
+	result, err := service.ValidateTokenDetailed(foreignToken)
+	if !errors.Is(err, auth.ErrInvalidIssuer) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrInvalidIssuer, err)
+	}
+	if result.Expired {
+		t.Errorf("expected expired token with another issuer not to be reported as expired")
+	}
+
+	revokedToken := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"iss": "https://issuer.example.com",
+		"jti": "token_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	})
+	store.Revoke("token_1", time.Now().Add(time.Minute))
+	result, err = service.ValidateTokenDetailed(revokedToken)
+	if !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrTokenRevoked, err)
+	}
+	if result.Expired {
+		t.Errorf("expected expired and revoked token not to be reported as expired")
+	}
+}
+
+func TestServiceWithHTTPClient(t *testing.T) {
+	privateKey, keySet := newTestKey(t)
+	token := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+
+	transport := &recordingTransport{
+		responses: map[string]string{
+			"https://idp.test/userinfo": `{"sub":"user_1"}`,
+		},
+	}
+	service := auth.NewService(
+		auth.Conf{
+			KeySet:    keySet,
+			DomainURL: "https://idp.test",
+		},
+		auth.ServiceWithHTTPClient(&http.Client{Transport: transport}),
+	)
+
+	userInfo, err := service.UserInfo(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if userInfo.ID != "user_1" {
+		t.Errorf("expected user ID to be 'user_1', got '%s'", userInfo.ID)
+	}
+
+	requests := transport.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("expected a single request, got %d", len(requests))
+	}
+	if header := requests[0].Header.Get("Authorization"); header != "Bearer "+token {
+		t.Errorf("expected the access token to be sent, got '%s'", header)
+	}
+}
+
+func TestServiceIssuerAndAudience(t *testing.T) {
+	privateKey, keySet := newTestKey(t)
+	service := auth.NewService(auth.Conf{
+		KeySet:           keySet,
+		ExpectedIssuer:   "https://idp.test/",
+		ExpectedAudience: []string{"api_1", "api_2"},
+	})
+
+	tests := []struct {
+		name     string
+		issuer   string
+		audience any
+		err      error
+	}{
+		{"valid", "https://idp.test/", "api_1", nil},
+		{"valid audience list", "https://idp.test/", []string{"other", "api_2"}, nil},
+		{"wrong issuer", "https://other.test/", "api_1", auth.ErrInvalidIssuer},
+		{"wrong audience", "https://idp.test/", "other", auth.ErrInvalidAudience},
+		{"missing audience", "https://idp.test/", nil, auth.ErrInvalidAudience},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := auth.MapClaims{
+				"sub": "user_1",
+				"iss": test.issuer,
+				"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			}
+			if test.audience != nil {
+				claims["aud"] = test.audience
+			}
+			token := signTestToken(t, privateKey, claims)
+
+			if err := service.ValidateToken(token); !errors.Is(err, test.err) {
+				t.Errorf("expected error to be '%v', got '%v'", test.err, err)
+			}
+
+			if _, err := service.ValidateTokenDetailed(token); !errors.Is(err, test.err) {
+				t.Errorf("expected detailed validation error to be '%v', got '%v'", test.err, err)
+			}
+		})
+	}
+}
+
+func TestServiceLeeway(t *testing.T) {
+	privateKey, keySet := newTestKey(t)
+	expiredToken := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(-2 * time.Second)),
+	})
+	notValidYetToken := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"nbf": jwt.NewNumericDate(time.Now().Add(2 * time.Second)),
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+
+	strictService := auth.NewService(auth.Conf{KeySet: keySet})
+	if err := strictService.ValidateToken(expiredToken); !errors.Is(err, auth.ErrTokenExpired) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrTokenExpired, err)
+	}
+	if err := strictService.ValidateToken(notValidYetToken); !errors.Is(err, auth.ErrTokenNotValidYet) {
+		t.Errorf("expected error to be '%v', got '%v'", auth.ErrTokenNotValidYet, err)
+	}
+
+	lenientService := auth.NewService(auth.Conf{
+		KeySet: keySet,
+		Leeway: 5 * time.Second,
+	})
+	if err := lenientService.ValidateToken(expiredToken); err != nil {
+		t.Errorf("expected expired token to be valid within the leeway, got '%v'", err)
+	}
+	if err := lenientService.ValidateToken(notValidYetToken); err != nil {
+		t.Errorf("expected not yet valid token to be valid within the leeway, got '%v'", err)
+	}
+
+	result, err := lenientService.ValidateTokenDetailed(expiredToken)
+	if err != nil || result.Expired {
+		t.Errorf("expected token within the leeway not to be reported as expired, got %+v (%v)", result, err)
+	}
+}
+
+func TestServiceUserInfoRoles(t *testing.T) {
+	privateKey, keySet := newTestKey(t)
+	token := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		"realm_access": map[string]any{
+			"roles": []string{"admin"},
+		},
+	})
+
+	service := auth.NewService(
+		auth.Conf{
+			KeySet:     keySet,
+			DomainURL:  "https://idp.test",
+			RolesClaim: "realm_access.roles",
+		},
+		auth.ServiceWithHTTPClient(&http.Client{
+			Transport: &recordingTransport{
+				responses: map[string]string{
+					"https://idp.test/userinfo": `{"sub":"user_1"}`,
+				},
+			},
+		}),
+	)
+
+	userInfo, err := service.UserInfo(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(userInfo.Roles) != 1 || userInfo.Roles[0] != "admin" {
+		t.Errorf("expected roles to be [admin], got %v", userInfo.Roles)
+	}
+}
+
+func TestServiceHTTPTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		},
+	))
+	defer server.Close()
+	defer close(release)
+
+	privateKey, keySet := newTestKey(t)
+	token := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+
+	service := auth.NewService(auth.Conf{
+		KeySet:      keySet,
+		DomainURL:   server.URL,
+		HTTPTimeout: 20 * time.Millisecond,
+	})
+
+	start := time.Now()
+	if _, err := service.UserInfo(token); err == nil {
+		t.Errorf("expected the user info request to time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the request to be aborted promptly, took %s", elapsed)
+	}
+}
+
+func TestServiceHS256(t *testing.T) {
+	secret := []byte("internal_secret")
+	service := auth.NewService(auth.Conf{Secret: secret})
+
+	claims := auth.MapClaims{
+		"sub": "service_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+
+	token, err := auth.GenerateTokenHS256(claims, secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := service.ValidateToken(token); err != nil {
+		t.Errorf("expected HS256 token to be valid, got '%v'", err)
+	}
+
+	otherToken, err := auth.GenerateTokenHS256(claims, []byte("other_secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.ValidateToken(otherToken); err == nil {
+		t.Errorf("expected HS256 token signed with another secret to be rejected")
+	}
+
+	// RS256 tokens are rejected when only the shared secret is configured
+	privateKey, _ := newTestKey(t)
+	rsaToken := signTestToken(t, privateKey, claims)
+	if err := service.ValidateToken(rsaToken); err == nil {
+		t.Errorf("expected RS256 token to be rejected when only HS256 is configured")
+	}
+}
+
+func TestServiceHS256AlgorithmConfusion(t *testing.T) {
+	privateKey, keySet := newTestKey(t)
+	service := auth.NewService(auth.Conf{KeySet: keySet})
+
+	// HS256 token signed with the public RSA key material as secret
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	token.Header["kid"] = testKeyID
+	signedToken, err := token.SignedString(privateKey.PublicKey.N.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error signing token: %v", err)
+	}
+
+	if err := service.ValidateToken(signedToken); err == nil {
+		t.Errorf("expected HS256 token to be rejected when only a key set is configured")
+	}
+}
+
+func TestServiceSharedUserInfoFetch(t *testing.T) {
+	reached := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			reached <- struct{}{}
+			<-release
+			w.Write([]byte(`{"sub":"user_1"}`))
+		},
+	))
+	defer server.Close()
+
+	privateKey, keySet := newTestKey(t)
+	token := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+
+	userInfoCache := cache.New[string, *auth.UserInfo]()
+	defer userInfoCache.Close()
+	service := auth.NewService(
+		auth.Conf{KeySet: keySet, DomainURL: server.URL},
+		auth.ServiceWithUserInfoCache(userInfoCache),
+	)
+
+	// The first caller starts the shared fetch and goes away
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := service.UserInfoContext(ctx, token)
+		firstErr <- err
+	}()
+	<-reached
+
+	secondResult := make(chan error, 1)
+	go func() {
+		userInfo, err := service.UserInfoContext(context.Background(), token)
+		if err == nil && userInfo.ID != "user_1" {
+			err = fmt.Errorf("unexpected user '%s'", userInfo.ID)
+		}
+		secondResult <- err
+	}()
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error to be '%v', got '%v'", context.Canceled, err)
+	}
+
+	close(release)
+	if err := <-secondResult; err != nil {
+		t.Errorf("expected the other callers not to be failed by the first one, got '%v'", err)
+	}
+}
+
+func TestServiceUserInfoRequestFailed(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_token"}`))
+		},
+	))
+	defer server.Close()
+
+	privateKey, keySet := newTestKey(t)
+	token := signTestToken(t, privateKey, auth.MapClaims{
+		"sub": "user_1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+
+	userInfoCache := cache.New[string, *auth.UserInfo]()
+	defer userInfoCache.Close()
+	service := auth.NewService(
+		auth.Conf{KeySet: keySet, DomainURL: server.URL},
+		auth.ServiceWithUserInfoCache(userInfoCache),
+	)
+
+	for i := 0; i < 2; i++ {
+		if _, err := service.UserInfo(token); !errors.Is(err, auth.ErrUserInfoRequestFailed) {
+			t.Errorf("expected error to be '%v', got '%v'", auth.ErrUserInfoRequestFailed, err)
+		}
+	}
+	if count := calls.Load(); count != 2 {
+		t.Errorf("expected failed responses not to be cached, got %d requests", count)
+	}
+}
@@ -1,12 +1,30 @@
 package cache
 
 import (
+	"container/list"
+	"context"
+	"errors"
 	"sync"
 	"time"
 )
 
+// Error returned to the callers waiting on a load that panicked
+var ErrLoadPanicked = errors.New("cache load panicked")
+
 type Option[K comparable, V any] func(*Cache[K, V])
 
+// Reason for an item to be removed from the cache
+type EvictReason int
+
+const (
+	// The item TTL elapsed
+	EvictReasonExpired EvictReason = iota
+	// The item was removed with Unset
+	EvictReasonUnset
+	// The item was the least recently used one of a full cache
+	EvictReasonSize
+)
+
 func WithTTL[K comparable, V any](ttl time.Duration) Option[K, V] {
 	return func(c *Cache[K, V]) {
 		c.itemTTL = &ttl
@@ -19,82 +37,357 @@ func WithCleanupInterval[K comparable, V any](interval time.Duration) Option[K,
 	}
 }
 
+// Limits the number of items, evicting the least recently used item when exceeded
+func WithMaxSize[K comparable, V any](size int) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.maxSize = size
+	}
+}
+
+// Calls the given function whenever an item is removed from the cache. The
+// function is called without holding the cache lock so it may use the cache
+func WithOnEvict[K comparable, V any](onEvict func(key K, value V, reason EvictReason)) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.onEvict = onEvict
+	}
+}
+
+// Stops the cleanup of expired items when the given context is done
+func WithContext[K comparable, V any](ctx context.Context) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.ctx = ctx
+	}
+}
+
+// Counters of the stampede protection of GetOrSet
+type Stats struct {
+	// Loads shared by more than one concurrent GetOrSet call
+	CoalescedLoads uint64
+	// GetOrSet calls that waited on a load started by another call
+	DeduplicatedWaiters uint64
+}
+
 type Cache[K comparable, V any] struct {
-	items map[K]item[V]
+	items map[K]*list.Element
 	lock  sync.Mutex
 
+	// Loads in progress started by GetOrSet
+	loads map[K]*load[V]
+	stats Stats
+
+	// Items ordered from the most to the least recently used
+	recency *list.List
+	maxSize int
+	onEvict func(key K, value V, reason EvictReason)
+
 	itemTTL             *time.Duration
 	itemCleanupInterval time.Duration
+
+	ctx         context.Context
+	done        chan struct{}
+	cleanupOnce sync.Once
+	closeOnce   sync.Once
 }
 
+// Creates a new cache, callers should Close it (or cancel the context given with
+// WithContext) once it's no longer needed to stop the cleanup of expired items
 func New[K comparable, V any](
 	opts ...Option[K, V],
 ) *Cache[K, V] {
 	cache := &Cache[K, V]{
-		items:               make(map[K]item[V]),
+		items:               make(map[K]*list.Element),
+		loads:               make(map[K]*load[V]),
+		recency:             list.New(),
 		itemCleanupInterval: 10 * time.Second,
+		ctx:                 context.Background(),
+		done:                make(chan struct{}),
 	}
 
 	for _, opt := range opts {
 		opt(cache)
 	}
 
-	go func() {
-		if cache.itemTTL == nil {
+	if cache.itemTTL != nil {
+		cache.startCleanup()
+	}
+
+	return cache
+}
+
+// Starts the cleanup of expired items, only once items can expire
+func (c *Cache[K, V]) startCleanup() {
+	c.cleanupOnce.Do(func() {
+		go c.cleanup()
+	})
+}
+
+func (c *Cache[K, V]) cleanup() {
+	ticker := time.NewTicker(c.itemCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
 			return
-		}
+		case <-c.done:
+			return
+		case <-ticker.C:
+			var expired []*item[K, V]
 
-		for range time.Tick(cache.itemCleanupInterval) {
-			cache.lock.Lock()
-			for key, item := range cache.items {
-				if item.isExpired() {
-					delete(cache.items, key)
+			c.lock.Lock()
+			for _, element := range c.items {
+				if element.Value.(*item[K, V]).isExpired() {
+					expired = append(expired, c.remove(element))
 				}
 			}
-			cache.lock.Unlock()
+			c.lock.Unlock()
+
+			for _, item := range expired {
+				c.notifyEvict(item, EvictReasonExpired)
+			}
 		}
-	}()
+	}
+}
 
-	return cache
+// Stops the cleanup of expired items
+func (c *Cache[K, V]) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
 }
 
 func (c *Cache[K, V]) Get(key K) (V, bool) {
 	c.lock.Lock()
-	defer c.lock.Unlock()
+	value, expired, found := c.get(key)
+	c.lock.Unlock()
 
-	value, found := c.items[key]
+	c.notifyEvict(expired, EvictReasonExpired)
 
-	return value.value, found
+	return value, found
+}
+
+// Gets the value for the given key, expired items are removed and returned to
+// be notified once the lock is released
+func (c *Cache[K, V]) get(key K) (V, *item[K, V], bool) {
+	var zero V
+
+	element, found := c.items[key]
+	if !found {
+		return zero, nil, false
+	}
+
+	if element.Value.(*item[K, V]).isExpired() {
+		return zero, c.remove(element), false
+	}
+	c.recency.MoveToFront(element)
+
+	return element.Value.(*item[K, V]).value, nil, true
 }
 
 func (c *Cache[K, V]) Set(key K, value V) {
 	c.lock.Lock()
-	defer c.lock.Unlock()
+	evicted := c.set(key, value, c.itemTTL)
+	c.lock.Unlock()
 
+	c.notifyEvict(evicted, EvictReasonSize)
+}
+
+// Sets the value for the given key overriding the cache TTL for that item
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.startCleanup()
+
+	c.lock.Lock()
+	evicted := c.set(key, value, &ttl)
+	c.lock.Unlock()
+
+	c.notifyEvict(evicted, EvictReasonSize)
+}
+
+// Sets the item returning the one evicted to make room for it if any
+func (c *Cache[K, V]) set(key K, value V, itemTTL *time.Duration) *item[K, V] {
 	var ttl *time.Time
-	if c.itemTTL != nil {
-		ttl = ptr(time.Now().Add(*c.itemTTL))
+	if itemTTL != nil {
+		ttl = ptr(time.Now().Add(*itemTTL))
 	}
 
-	c.items[key] = item[V]{
+	newItem := &item[K, V]{
+		key:   key,
 		value: value,
 		ttl:   ttl,
 	}
+
+	if element, found := c.items[key]; found {
+		element.Value = newItem
+		c.recency.MoveToFront(element)
+
+		return nil
+	}
+
+	c.items[key] = c.recency.PushFront(newItem)
+
+	// Evict the least recently used item when the cache is full
+	if c.maxSize > 0 && c.recency.Len() > c.maxSize {
+		return c.remove(c.recency.Back())
+	}
+
+	return nil
+}
+
+// Returns the value for the given key, loading and setting it when not found.
+// Concurrent calls for the same key share a single load, which runs without
+// holding the cache lock
+func (c *Cache[K, V]) GetOrSet(key K, loadValue func() (V, error)) (V, error) {
+	c.lock.Lock()
+	value, expired, found := c.get(key)
+	if found {
+		c.lock.Unlock()
+		return value, nil
+	}
+
+	l, loading := c.loads[key]
+	if loading {
+		// Count the load as coalesced once it gets its first waiter
+		if l.waiters == 0 {
+			c.stats.CoalescedLoads++
+		}
+		l.waiters++
+		c.stats.DeduplicatedWaiters++
+	} else {
+		l = &load[V]{done: make(chan struct{})}
+		c.loads[key] = l
+	}
+	c.lock.Unlock()
+
+	c.notifyEvict(expired, EvictReasonExpired)
+
+	// Wait for the load in progress if any
+	if loading {
+		<-l.done
+
+		return l.value, l.err
+	}
+
+	completed := false
+	defer func() {
+		if !completed {
+			l.err = ErrLoadPanicked
+		}
+
+		var evicted *item[K, V]
+
+		c.lock.Lock()
+		delete(c.loads, key)
+		if l.err == nil {
+			evicted = c.set(key, l.value, c.itemTTL)
+		}
+		c.lock.Unlock()
+
+		close(l.done)
+		c.notifyEvict(evicted, EvictReasonSize)
+	}()
+
+	l.value, l.err = loadValue()
+	completed = true
+
+	return l.value, l.err
+}
+
+// Returns the counters of the stampede protection of GetOrSet
+func (c *Cache[K, V]) Stats() Stats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.stats
 }
 
 func (c *Cache[K, V]) Unset(key K) {
+	var removed *item[K, V]
+
+	c.lock.Lock()
+	if element, found := c.items[key]; found {
+		removed = c.remove(element)
+	}
+	c.lock.Unlock()
+
+	c.notifyEvict(removed, EvictReasonUnset)
+}
+
+// Returns the number of unexpired items
+func (c *Cache[K, V]) Len() int {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	delete(c.items, key)
+	count := 0
+	for _, element := range c.items {
+		if !element.Value.(*item[K, V]).isExpired() {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Returns the keys of the unexpired items, from the most to the least recently used
+func (c *Cache[K, V]) Keys() []K {
+	items := c.unexpiredItems()
+
+	keys := make([]K, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, item.key)
+	}
+
+	return keys
+}
+
+// Calls the given function for each unexpired item, from the most to the least
+// recently used, stopping when it returns false. The function is called without
+// holding the cache lock so it may use the cache
+func (c *Cache[K, V]) Range(f func(key K, value V) bool) {
+	for _, item := range c.unexpiredItems() {
+		if !f(item.key, item.value) {
+			return
+		}
+	}
+}
+
+func (c *Cache[K, V]) unexpiredItems() []*item[K, V] {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	items := make([]*item[K, V], 0, c.recency.Len())
+	for element := c.recency.Front(); element != nil; element = element.Next() {
+		item := element.Value.(*item[K, V])
+		if !item.isExpired() {
+			items = append(items, item)
+		}
+	}
+
+	return items
 }
 
-type item[V any] struct {
+func (c *Cache[K, V]) remove(element *list.Element) *item[K, V] {
+	item := element.Value.(*item[K, V])
+	c.recency.Remove(element)
+	delete(c.items, item.key)
+
+	return item
+}
+
+func (c *Cache[K, V]) notifyEvict(item *item[K, V], reason EvictReason) {
+	if c.onEvict == nil || item == nil {
+		return
+	}
+
+	c.onEvict(item.key, item.value, reason)
+}
+
+type item[K comparable, V any] struct {
+	key   K
 	value V
 	ttl   *time.Time
 }
 
-func (i item[V]) isExpired() bool {
+func (i *item[K, V]) isExpired() bool {
 	if i.ttl == nil {
 		return false
 	}
@@ -102,6 +395,13 @@ func (i item[V]) isExpired() bool {
 	return time.Now().After(*i.ttl)
 }
 
+type load[V any] struct {
+	done    chan struct{}
+	value   V
+	err     error
+	waiters int
+}
+
 func ptr[T any](v T) *T {
 	return &v
 }
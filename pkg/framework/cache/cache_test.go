@@ -1,7 +1,14 @@
 package cache_test
 
 import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/sergioneiravargas/template-go/pkg/framework/cache"
 )
@@ -48,3 +55,384 @@ func TestCache(t *testing.T) {
 		t.Errorf("expected value not to be found for key '%s'", key2)
 	}
 }
+
+func TestCacheWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	goroutines := runtime.NumGoroutine()
+	cache.New[string, string](
+		cache.WithTTL[string, string](time.Minute),
+		cache.WithCleanupInterval[string, string](time.Millisecond),
+		cache.WithContext[string, string](ctx),
+	)
+
+	if runtime.NumGoroutine() <= goroutines {
+		t.Fatalf("expected the cleanup goroutine to be running")
+	}
+
+	cancel()
+	if !waitForGoroutines(goroutines) {
+		t.Errorf("expected the cleanup goroutine to exit after the context is cancelled")
+	}
+}
+
+func TestCacheClose(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	c := cache.New[string, string](
+		cache.WithTTL[string, string](time.Minute),
+		cache.WithCleanupInterval[string, string](time.Millisecond),
+	)
+
+	if runtime.NumGoroutine() <= goroutines {
+		t.Fatalf("expected the cleanup goroutine to be running")
+	}
+
+	c.Close()
+	if !waitForGoroutines(goroutines) {
+		t.Errorf("expected the cleanup goroutine to exit after closing the cache")
+	}
+
+	// Closing twice must not panic
+	c.Close()
+}
+
+func waitForGoroutines(count int) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if runtime.NumGoroutine() <= count {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	return false
+}
+
+func TestCacheMaxSize(t *testing.T) {
+	c := cache.New[string, string](
+		cache.WithMaxSize[string, string](2),
+	)
+
+	c.Set("key_1", "value_1")
+	c.Set("key_2", "value_2")
+	c.Set("key_3", "value_3")
+
+	if _, found := c.Get("key_1"); found {
+		t.Errorf("expected least recently used key 'key_1' to be evicted")
+	}
+
+	// Accessing key_2 makes key_3 the least recently used
+	if _, found := c.Get("key_2"); !found {
+		t.Errorf("expected value to be found for key 'key_2'")
+	}
+
+	c.Set("key_4", "value_4")
+
+	if _, found := c.Get("key_3"); found {
+		t.Errorf("expected least recently used key 'key_3' to be evicted")
+	}
+
+	for _, key := range []string{"key_2", "key_4"} {
+		if _, found := c.Get(key); !found {
+			t.Errorf("expected value to be found for key '%s'", key)
+		}
+	}
+
+	// Overwriting an existing key doesn't evict anything
+	c.Set("key_2", "value_2_updated")
+
+	value, found := c.Get("key_2")
+	if !found || value != "value_2_updated" {
+		t.Errorf("expected value to be 'value_2_updated'")
+	}
+
+	if _, found := c.Get("key_4"); !found {
+		t.Errorf("expected value to be found for key 'key_4'")
+	}
+}
+
+func TestCacheGetOrSet(t *testing.T) {
+	c := cache.New[string, string]()
+
+	var loadCount atomic.Int32
+	release := make(chan struct{})
+	load := func() (string, error) {
+		loadCount.Add(1)
+		<-release
+
+		return "value_1", nil
+	}
+
+	callerCount := 50
+	values := make(chan string, callerCount)
+	var wg sync.WaitGroup
+	for i := 0; i < callerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			value, err := c.GetOrSet("key_1", load)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			values <- value
+		}()
+	}
+
+	// Give the callers time to pile up on the load in progress
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(values)
+
+	if count := loadCount.Load(); count != 1 {
+		t.Errorf("expected load to be invoked once, got %d", count)
+	}
+
+	for value := range values {
+		if value != "value_1" {
+			t.Errorf("expected value to be 'value_1', got '%s'", value)
+		}
+	}
+
+	if value, found := c.Get("key_1"); !found || value != "value_1" {
+		t.Errorf("expected loaded value to be set")
+	}
+}
+
+func TestCacheGetOrSetStats(t *testing.T) {
+	c := cache.New[string, string]()
+
+	release := make(chan struct{})
+	load := func() (string, error) {
+		<-release
+
+		return "value_1", nil
+	}
+
+	callerCount := 50
+	var wg sync.WaitGroup
+	for i := 0; i < callerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := c.GetOrSet("key_1", load); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+
+	// Wait for every other caller to join the load in progress
+	deadline := time.Now().Add(time.Second)
+	for c.Stats().DeduplicatedWaiters < uint64(callerCount-1) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	stats := c.Stats()
+	if stats.CoalescedLoads != 1 {
+		t.Errorf("expected 1 coalesced load, got %d", stats.CoalescedLoads)
+	}
+	if stats.DeduplicatedWaiters != uint64(callerCount-1) {
+		t.Errorf("expected %d deduplicated waiters, got %d", callerCount-1, stats.DeduplicatedWaiters)
+	}
+
+	// Loads without concurrent callers are not coalesced
+	if _, err := c.GetOrSet("key_2", func() (string, error) { return "value_2", nil }); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if stats := c.Stats(); stats.CoalescedLoads != 1 {
+		t.Errorf("expected 1 coalesced load, got %d", stats.CoalescedLoads)
+	}
+}
+
+func TestCacheGetOrSetError(t *testing.T) {
+	c := cache.New[string, string]()
+	loadErr := errors.New("load failed")
+
+	_, err := c.GetOrSet("key_1", func() (string, error) {
+		return "", loadErr
+	})
+	if !errors.Is(err, loadErr) {
+		t.Errorf("expected error to be '%v', got '%v'", loadErr, err)
+	}
+
+	if _, found := c.Get("key_1"); found {
+		t.Errorf("expected failed load not to be set")
+	}
+
+	func() {
+		defer func() { recover() }()
+		c.GetOrSet("key_1", func() (string, error) {
+			panic("load panic")
+		})
+	}()
+
+	if _, found := c.Get("key_1"); found {
+		t.Errorf("expected panicked load not to be set")
+	}
+}
+
+func TestCacheLenKeysRange(t *testing.T) {
+	c := cache.New[string, string](
+		cache.WithTTL[string, string](20*time.Millisecond),
+		cache.WithCleanupInterval[string, string](time.Hour),
+	)
+	defer c.Close()
+
+	c.Set("key_1", "value_1")
+	time.Sleep(40 * time.Millisecond)
+	c.Set("key_2", "value_2")
+	c.Set("key_3", "value_3")
+
+	// key_1 is expired but not cleaned up yet
+	if length := c.Len(); length != 2 {
+		t.Errorf("expected length to be 2, got %d", length)
+	}
+
+	keys := c.Keys()
+	if !slices.Equal(keys, []string{"key_3", "key_2"}) {
+		t.Errorf("expected keys to be [key_3 key_2], got %v", keys)
+	}
+
+	ranged := map[string]string{}
+	c.Range(func(key string, value string) bool {
+		ranged[key] = value
+		return true
+	})
+	if len(ranged) != 2 || ranged["key_2"] != "value_2" || ranged["key_3"] != "value_3" {
+		t.Errorf("expected range to visit the unexpired items, got %v", ranged)
+	}
+
+	visited := 0
+	c.Range(func(key string, value string) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("expected range to stop after the first item, visited %d", visited)
+	}
+}
+
+func TestCacheSetWithTTL(t *testing.T) {
+	c := cache.New[string, string](
+		cache.WithTTL[string, string](time.Hour),
+		cache.WithCleanupInterval[string, string](5*time.Millisecond),
+	)
+	defer c.Close()
+
+	c.SetWithTTL("short", "value_1", 10*time.Millisecond)
+	c.Set("long", "value_2")
+
+	time.Sleep(50 * time.Millisecond)
+
+	if _, found := c.Get("short"); found {
+		t.Errorf("expected short TTL item to be expired")
+	}
+
+	if _, found := c.Get("long"); !found {
+		t.Errorf("expected long TTL item to be found")
+	}
+}
+
+func TestCacheSetWithTTLWithoutDefaultTTL(t *testing.T) {
+	c := cache.New[string, string](
+		cache.WithCleanupInterval[string, string](5 * time.Millisecond),
+	)
+	defer c.Close()
+
+	c.SetWithTTL("short", "value_1", 10*time.Millisecond)
+	c.Set("forever", "value_2")
+
+	time.Sleep(50 * time.Millisecond)
+
+	if _, found := c.Get("short"); found {
+		t.Errorf("expected short TTL item to be expired")
+	}
+
+	if _, found := c.Get("forever"); !found {
+		t.Errorf("expected item without TTL to be found")
+	}
+}
+
+func TestCacheOnEvict(t *testing.T) {
+	type eviction struct {
+		key    string
+		value  string
+		reason cache.EvictReason
+	}
+
+	var lock sync.Mutex
+	var evictions []eviction
+	var c *cache.Cache[string, string]
+	c = cache.New[string, string](
+		cache.WithMaxSize[string, string](2),
+		cache.WithCleanupInterval[string, string](5*time.Millisecond),
+		cache.WithOnEvict[string, string](func(key string, value string, reason cache.EvictReason) {
+			// Using the cache from the callback must not deadlock
+			c.Len()
+
+			lock.Lock()
+			defer lock.Unlock()
+			evictions = append(evictions, eviction{key, value, reason})
+		}),
+	)
+	defer c.Close()
+
+	c.Set("key_1", "value_1")
+	c.Set("key_2", "value_2")
+	c.Set("key_3", "value_3")
+	c.Unset("key_2")
+	c.SetWithTTL("key_4", "value_4", time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		lock.Lock()
+		count := len(evictions)
+		lock.Unlock()
+		if count >= 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	expected := []eviction{
+		{"key_1", "value_1", cache.EvictReasonSize},
+		{"key_2", "value_2", cache.EvictReasonUnset},
+		{"key_4", "value_4", cache.EvictReasonExpired},
+	}
+	if !slices.Equal(evictions, expected) {
+		t.Errorf("expected evictions to be %v, got %v", expected, evictions)
+	}
+}
+
+func TestCacheGetExpired(t *testing.T) {
+	c := cache.New[string, string](
+		cache.WithTTL[string, string](10*time.Millisecond),
+		cache.WithCleanupInterval[string, string](time.Hour),
+	)
+	defer c.Close()
+
+	c.Set("key_1", "value_1")
+	time.Sleep(30 * time.Millisecond)
+
+	value, found := c.Get("key_1")
+	if found {
+		t.Errorf("expected expired value not to be found for key 'key_1'")
+	}
+	if value != "" {
+		t.Errorf("expected zero value for an expired item, got '%s'", value)
+	}
+
+	loaded, err := c.GetOrSet("key_1", func() (string, error) {
+		return "value_2", nil
+	})
+	if err != nil || loaded != "value_2" {
+		t.Errorf("expected expired item to be reloaded, got '%s' (%v)", loaded, err)
+	}
+}
@@ -0,0 +1,30 @@
+package compress
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Content types compressed by default, already compressed ones are left out
+var DefaultContentTypes = []string{
+	"application/json",
+	"application/problem+json",
+	"text/html",
+	"text/plain",
+	"text/css",
+	"text/javascript",
+}
+
+// Middleware that compresses the responses with the encoding negotiated via
+// Accept-Encoding at the given level
+func Middleware(
+	level int,
+	contentTypes ...string,
+) func(next http.Handler) http.Handler {
+	if len(contentTypes) == 0 {
+		contentTypes = DefaultContentTypes
+	}
+
+	return middleware.Compress(level, contentTypes...)
+}
@@ -0,0 +1,64 @@
+package compress_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/compress"
+)
+
+const body = `{"message":"Hello, World!"}`
+
+func newHandler(contentType string) http.Handler {
+	return compress.Middleware(5)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.Write([]byte(body))
+		},
+	))
+}
+
+func TestMiddleware(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res := httptest.NewRecorder()
+	newHandler("application/json").ServeHTTP(res, req)
+
+	if encoding := res.Header().Get("Content-Encoding"); encoding != "gzip" {
+		t.Fatalf("expected content encoding 'gzip', got '%s'", encoding)
+	}
+
+	reader, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(decoded) != body {
+		t.Errorf("expected decoded body '%s', got '%s'", body, decoded)
+	}
+}
+
+func TestMiddlewareSkipped(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	res := httptest.NewRecorder()
+	newHandler("application/json").ServeHTTP(res, req)
+
+	if encoding := res.Header().Get("Content-Encoding"); encoding != "" {
+		t.Errorf("expected no content encoding without Accept-Encoding, got '%s'", encoding)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res = httptest.NewRecorder()
+	newHandler("image/png").ServeHTTP(res, req)
+
+	if encoding := res.Header().Get("Content-Encoding"); encoding != "" {
+		t.Errorf("expected no content encoding for compressed content types, got '%s'", encoding)
+	}
+}
@@ -0,0 +1,58 @@
+package framework
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/log"
+	"github.com/sergioneiravargas/template-go/pkg/framework/sql"
+
+	"go.uber.org/fx"
+)
+
+// Configuration of the framework module
+type Conf struct {
+	Name    string
+	Env     string
+	SQLConf sql.Conf
+}
+
+// Module providing the logger and the SQL connection pair from a Conf
+var Module = fx.Module(
+	"framework",
+	fx.Provide(
+		NewLogger,
+		NewSQLDBPair,
+	),
+)
+
+// Creates the application logger writing to the standard output
+func NewLogger(
+	conf Conf,
+) *log.Logger {
+	handler := log.NewHandler(os.Stdout, conf.Env)
+
+	return log.NewLogger(
+		conf.Name,
+		handler,
+	)
+}
+
+// Creates the SQL connection pair, logging its pool stats while the application runs
+func NewSQLDBPair(
+	lc fx.Lifecycle,
+	conf Conf,
+	logger *log.Logger,
+) (sql.DBPair, error) {
+	dbPair, err := sql.NewDBPairWithContext(
+		context.Background(),
+		conf.SQLConf,
+	)
+	if err != nil {
+		return sql.DBPair{}, err
+	}
+	lc.Append(fx.StopHook(sql.StartPairStatsLogger(dbPair, logger, time.Minute)))
+
+	return dbPair, nil
+}
@@ -0,0 +1,32 @@
+package framework_test
+
+import (
+	"testing"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework"
+	"github.com/sergioneiravargas/template-go/pkg/framework/log"
+	"github.com/sergioneiravargas/template-go/pkg/framework/sql"
+
+	"go.uber.org/fx"
+)
+
+func TestModule(t *testing.T) {
+	err := fx.ValidateApp(
+		fx.Supply(framework.Conf{}),
+		framework.Module,
+		fx.Invoke(func(*log.Logger, sql.DBPair) {}),
+	)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestModuleMissingConf(t *testing.T) {
+	err := fx.ValidateApp(
+		framework.Module,
+		fx.Invoke(func(*log.Logger) {}),
+	)
+	if err == nil {
+		t.Errorf("expected an error when the framework configuration is missing")
+	}
+}
@@ -0,0 +1,69 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	// Check statuses
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Named check of a dependency, healthy when it returns no error
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Result of a check as exposed by the handler
+type CheckResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report of all the checks as exposed by the handler
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Handler that runs the given checks, responding with 200 when all of them
+// pass and 503 otherwise
+func Handler(
+	checks ...Check,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := Report{
+			Status: StatusOK,
+			Checks: make([]CheckResult, 0, len(checks)),
+		}
+		for _, check := range checks {
+			result := CheckResult{
+				Name:   check.Name,
+				Status: StatusOK,
+			}
+			if err := check.Check(r.Context()); err != nil {
+				result.Status = StatusUnavailable
+				result.Error = err.Error()
+				report.Status = StatusUnavailable
+			}
+			report.Checks = append(report.Checks, result)
+		}
+
+		body, err := json.Marshal(report)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != StatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write(body)
+	}
+}
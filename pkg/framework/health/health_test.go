@@ -0,0 +1,85 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/health"
+)
+
+func TestHandler(t *testing.T) {
+	passingCheck := health.Check{
+		Name: "sql",
+		Check: func(ctx context.Context) error {
+			return nil
+		},
+	}
+	failingCheck := health.Check{
+		Name: "cache",
+		Check: func(ctx context.Context) error {
+			return errors.New("connection refused")
+		},
+	}
+
+	tests := []struct {
+		name           string
+		checks         []health.Check
+		expectedCode   int
+		expectedStatus string
+	}{
+		{"no checks", nil, http.StatusOK, health.StatusOK},
+		{"healthy", []health.Check{passingCheck}, http.StatusOK, health.StatusOK},
+		{"failing dependency", []health.Check{passingCheck, failingCheck}, http.StatusServiceUnavailable, health.StatusUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			health.Handler(tt.checks...).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if res.Code != tt.expectedCode {
+				t.Errorf("expected status code %d, got %d", tt.expectedCode, res.Code)
+			}
+
+			var report health.Report
+			if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+				t.Fatalf("unexpected error decoding response: %v", err)
+			}
+
+			if report.Status != tt.expectedStatus {
+				t.Errorf("expected status '%s', got '%s'", tt.expectedStatus, report.Status)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Fatalf("expected %d check results, got %d", len(tt.checks), len(report.Checks))
+			}
+			for i, result := range report.Checks {
+				if result.Name != tt.checks[i].Name {
+					t.Errorf("expected check name '%s', got '%s'", tt.checks[i].Name, result.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerFailingCheckError(t *testing.T) {
+	res := httptest.NewRecorder()
+	health.Handler(health.Check{
+		Name: "sql",
+		Check: func(ctx context.Context) error {
+			return errors.New("connection refused")
+		},
+	}).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report health.Report
+	if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+		t.Fatalf("unexpected error decoding response: %v", err)
+	}
+
+	if report.Checks[0].Status != health.StatusUnavailable || report.Checks[0].Error != "connection refused" {
+		t.Errorf("expected failing check result, got %+v", report.Checks[0])
+	}
+}
@@ -0,0 +1,77 @@
+package httpconf
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-chi/cors"
+)
+
+var (
+	ErrMissingAllowedOrigins = errors.New("missing CORS allowed origins")
+)
+
+var (
+	// Permissive origins used in the dev environment when none are configured
+	DefaultAllowedOrigins = []string{"*"}
+
+	DefaultAllowedMethods = []string{"HEAD", "GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	DefaultAllowedHeaders = []string{"Accept", "Authorization", "Content-Type"}
+)
+
+type CORSConf struct {
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+}
+
+// Creates the CORS configuration from comma-separated lists, the origins only
+// fall back to the permissive defaults in the dev environment
+func NewCORSConf(
+	env string,
+	allowedOrigins string,
+	allowedMethods string,
+	allowedHeaders string,
+) (CORSConf, error) {
+	conf := CORSConf{
+		AllowedOrigins: ParseList(allowedOrigins),
+		AllowedMethods: ParseList(allowedMethods),
+		AllowedHeaders: ParseList(allowedHeaders),
+	}
+
+	if len(conf.AllowedOrigins) == 0 {
+		if env != "dev" {
+			return CORSConf{}, ErrMissingAllowedOrigins
+		}
+		conf.AllowedOrigins = DefaultAllowedOrigins
+	}
+	if len(conf.AllowedMethods) == 0 {
+		conf.AllowedMethods = DefaultAllowedMethods
+	}
+	if len(conf.AllowedHeaders) == 0 {
+		conf.AllowedHeaders = DefaultAllowedHeaders
+	}
+
+	return conf, nil
+}
+
+// Returns the options of the CORS middleware
+func (c CORSConf) Options() cors.Options {
+	return cors.Options{
+		AllowedOrigins: c.AllowedOrigins,
+		AllowedMethods: c.AllowedMethods,
+		AllowedHeaders: c.AllowedHeaders,
+	}
+}
+
+// Splits a comma-separated list, ignoring blank items
+func ParseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
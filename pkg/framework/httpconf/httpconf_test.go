@@ -0,0 +1,72 @@
+package httpconf_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/go-chi/cors"
+	"github.com/sergioneiravargas/template-go/pkg/framework/httpconf"
+)
+
+func TestNewCORSConf(t *testing.T) {
+	conf, err := httpconf.NewCORSConf("dev", "", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(conf.AllowedOrigins, httpconf.DefaultAllowedOrigins) {
+		t.Errorf("expected default origins in dev, got %v", conf.AllowedOrigins)
+	}
+
+	if _, err := httpconf.NewCORSConf("prod", "", "", ""); !errors.Is(err, httpconf.ErrMissingAllowedOrigins) {
+		t.Errorf("expected error '%s', got '%v'", httpconf.ErrMissingAllowedOrigins, err)
+	}
+
+	conf, err = httpconf.NewCORSConf("prod", "https://a.example.com, https://b.example.com,", "GET", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedOrigins := []string{"https://a.example.com", "https://b.example.com"}
+	if !slices.Equal(conf.AllowedOrigins, expectedOrigins) {
+		t.Errorf("expected origins %v, got %v", expectedOrigins, conf.AllowedOrigins)
+	}
+	if !slices.Equal(conf.AllowedMethods, []string{"GET"}) {
+		t.Errorf("expected methods [GET], got %v", conf.AllowedMethods)
+	}
+	if !slices.Equal(conf.AllowedHeaders, httpconf.DefaultAllowedHeaders) {
+		t.Errorf("expected default headers, got %v", conf.AllowedHeaders)
+	}
+}
+
+func TestCORSConfOptions(t *testing.T) {
+	conf, err := httpconf.NewCORSConf("prod", "https://app.example.com", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handler := cors.Handler(conf.Options())(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {},
+	))
+
+	tests := []struct {
+		origin         string
+		expectedHeader string
+	}{
+		{"https://app.example.com", "https://app.example.com"},
+		{"https://evil.example.com", ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", tt.origin)
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		if header := res.Header().Get("Access-Control-Allow-Origin"); header != tt.expectedHeader {
+			t.Errorf("expected Access-Control-Allow-Origin '%s' for origin '%s', got '%s'", tt.expectedHeader, tt.origin, header)
+		}
+	}
+}
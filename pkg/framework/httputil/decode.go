@@ -0,0 +1,46 @@
+package httputil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrMalformedBody = errors.New("malformed request body")
+	ErrBodyTooLarge  = errors.New("request body too large")
+	ErrInvalidInput  = errors.New("invalid input")
+)
+
+const (
+	// Maximum size of the decoded request bodies
+	MaxBodySize = 1 << 20
+)
+
+// Input that can validate itself
+type Validatable interface {
+	Validate() error
+}
+
+// Decodes the JSON body of the request and validates it, every returned
+// error is a client error
+func DecodeAndValidate[T Validatable](r *http.Request) (T, error) {
+	var input T
+
+	body := http.MaxBytesReader(nil, r.Body, MaxBodySize)
+	if err := json.NewDecoder(body).Decode(&input); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return input, ErrBodyTooLarge
+		}
+
+		return input, fmt.Errorf("%w: %w", ErrMalformedBody, err)
+	}
+
+	if err := input.Validate(); err != nil {
+		return input, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+
+	return input, nil
+}
@@ -0,0 +1,61 @@
+package httputil_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/httputil"
+)
+
+var errMissingName = errors.New("missing name")
+
+type createUserInput struct {
+	Name string `json:"name"`
+}
+
+func (i createUserInput) Validate() error {
+	if i.Name == "" {
+		return errMissingName
+	}
+
+	return nil
+}
+
+func TestDecodeAndValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedErr  error
+		expectedName string
+	}{
+		{"valid input", `{"name":"Sergio"}`, nil, "Sergio"},
+		{"malformed JSON", `{"name":`, httputil.ErrMalformedBody, ""},
+		{"oversized body", `{"name":"` + strings.Repeat("a", httputil.MaxBodySize) + `"}`, httputil.ErrBodyTooLarge, ""},
+		{"validation failure", `{"name":""}`, httputil.ErrInvalidInput, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+
+			input, err := httputil.DecodeAndValidate[createUserInput](req)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected error '%v', got '%v'", tt.expectedErr, err)
+			}
+			if tt.expectedErr == nil && input.Name != tt.expectedName {
+				t.Errorf("expected name '%s', got '%s'", tt.expectedName, input.Name)
+			}
+		})
+	}
+}
+
+func TestDecodeAndValidateWrapsValidationError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{}`))
+
+	if _, err := httputil.DecodeAndValidate[createUserInput](req); !errors.Is(err, errMissingName) {
+		t.Errorf("expected error '%s', got '%v'", errMissingName, err)
+	}
+}
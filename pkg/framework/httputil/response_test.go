@@ -0,0 +1,54 @@
+package httputil_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/httputil"
+)
+
+func TestWriteJSON(t *testing.T) {
+	res := httptest.NewRecorder()
+	httputil.WriteJSON(res, http.StatusCreated, map[string]string{"message": "created"})
+
+	if res.Code != http.StatusCreated {
+		t.Errorf("expected status code %d, got %d", http.StatusCreated, res.Code)
+	}
+	if contentType := res.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("expected content type 'application/json', got '%s'", contentType)
+	}
+	if res.Body.String() != `{"message":"created"}` {
+		t.Errorf("expected body '%s', got '%s'", `{"message":"created"}`, res.Body.String())
+	}
+}
+
+func TestWriteJSONUnsupportedValue(t *testing.T) {
+	res := httptest.NewRecorder()
+	httputil.WriteJSON(res, http.StatusOK, make(chan int))
+
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("expected status code %d, got %d", http.StatusInternalServerError, res.Code)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	res := httptest.NewRecorder()
+	httputil.WriteError(res, http.StatusNotFound, "not_found", "Resource not found")
+
+	if res.Code != http.StatusNotFound {
+		t.Errorf("expected status code %d, got %d", http.StatusNotFound, res.Code)
+	}
+	if contentType := res.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("expected content type 'application/json', got '%s'", contentType)
+	}
+
+	var body map[string]map[string]string
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("unexpected error decoding response: %v", err)
+	}
+	if body["error"]["code"] != "not_found" || body["error"]["message"] != "Resource not found" {
+		t.Errorf("expected error code and message, got %v", body)
+	}
+}
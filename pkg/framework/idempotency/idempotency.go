@@ -0,0 +1,223 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/cache"
+)
+
+const (
+	// Request header carrying the idempotency key
+	KeyHeader = "Idempotency-Key"
+
+	// Response header set on replayed responses
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+// Number of responses kept, evicting the least recently used one
+const DefaultMaxResponses = 10_000
+
+// Response stored for an idempotency key
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+
+	// Hash of the body of the request the response was stored for
+	requestHash [sha256.Size]byte
+}
+
+// Responses by idempotency key and the keys being processed
+type Store struct {
+	responses *cache.Cache[string, *Response]
+
+	mu       sync.Mutex
+	inFlight map[string]struct{}
+}
+
+type StoreOption func(*storeOptions)
+
+type storeOptions struct {
+	maxResponses int
+}
+
+// Sets the number of responses kept, bounding the memory used by the store
+// regardless of the number of distinct keys
+func StoreWithMaxResponses(maxResponses int) StoreOption {
+	return func(o *storeOptions) {
+		o.maxResponses = maxResponses
+	}
+}
+
+// Creates a store keeping the responses for the given time
+func NewStore(
+	ttl time.Duration,
+	opts ...StoreOption,
+) *Store {
+	options := storeOptions{
+		maxResponses: DefaultMaxResponses,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &Store{
+		responses: cache.New[string, *Response](
+			cache.WithTTL[string, *Response](ttl),
+			cache.WithMaxSize[string, *Response](options.maxResponses),
+		),
+		inFlight: make(map[string]struct{}),
+	}
+}
+
+// Stops the expiration of the stored responses
+func (s *Store) Close() {
+	s.responses.Close()
+}
+
+// Marks the key as being processed, reporting false when it already was
+func (s *Store) lock(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.inFlight[key]; found {
+		return false
+	}
+	s.inFlight[key] = struct{}{}
+
+	return true
+}
+
+func (s *Store) unlock(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.inFlight, key)
+}
+
+// Middleware that replays the stored response of the requests carrying an
+// already processed idempotency key, responding with 409 while the first
+// request with that key is still being processed and with 422 when the key is
+// reused with a different request body
+//
+// It must be mounted inside response compression so that the stored
+// responses are not bound to the encoding of the first request.
+//
+// Keys are scoped by the value the scope function returns for the request,
+// e.g. the authenticated user, so that clients can't replay each other's
+// responses. Server errors, responses the handler didn't write and requests
+// cancelled or timed out are not stored so that the request can be retried
+func Middleware(
+	store *Store,
+	scopeFn func(*http.Request) string,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				idempotencyKey := r.Header.Get(KeyHeader)
+				if idempotencyKey == "" {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					http.Error(w, "Could not read the request body", http.StatusBadRequest)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				requestHash := sha256.Sum256(body)
+
+				key := scopeFn(r) + " " + r.Method + " " + r.URL.Path + " " + idempotencyKey
+				if response, found := store.responses.Get(key); found {
+					replay(w, response, requestHash)
+					return
+				}
+
+				if !store.lock(key) {
+					http.Error(w, "Request with the same idempotency key in progress", http.StatusConflict)
+					return
+				}
+				defer store.unlock(key)
+
+				// The first request may have finished before the key was locked
+				if response, found := store.responses.Get(key); found {
+					replay(w, response, requestHash)
+					return
+				}
+
+				recorder := &responseRecorder{ResponseWriter: w}
+				next.ServeHTTP(recorder, r)
+
+				// The client may have been sent another response, e.g. a timeout
+				if r.Context().Err() != nil {
+					return
+				}
+
+				response, written := recorder.response()
+				if written && response.Status < http.StatusInternalServerError {
+					response.requestHash = requestHash
+					store.responses.Set(key, response)
+				}
+			},
+		)
+	}
+}
+
+// Replays the stored response, unless it was stored for another request body
+func replay(w http.ResponseWriter, response *Response, requestHash [sha256.Size]byte) {
+	if response.requestHash != requestHash {
+		http.Error(w, "Idempotency key reused with a different request", http.StatusUnprocessableEntity)
+		return
+	}
+
+	for name, values := range response.Header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(response.Status)
+	w.Write(response.Body)
+}
+
+// Response writer keeping a copy of the written response
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		r.header = r.ResponseWriter.Header().Clone()
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(body []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(body)
+
+	return r.ResponseWriter.Write(body)
+}
+
+// Returns the recorded response, reporting false when the handler wrote nothing
+func (r *responseRecorder) response() (*Response, bool) {
+	if r.status == 0 {
+		return nil, false
+	}
+
+	return &Response{
+		Status: r.status,
+		Header: r.header,
+		Body:   r.body.Bytes(),
+	}, true
+}
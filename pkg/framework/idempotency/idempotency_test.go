@@ -0,0 +1,288 @@
+package idempotency_test
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/compress"
+	"github.com/sergioneiravargas/template-go/pkg/framework/idempotency"
+	"github.com/sergioneiravargas/template-go/pkg/framework/timeout"
+)
+
+func clientScope(r *http.Request) string {
+	return r.Header.Get("X-Client")
+}
+
+func newRequest(key string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/jobs", nil)
+	if key != "" {
+		req.Header.Set(idempotency.KeyHeader, key)
+	}
+
+	return req
+}
+
+func TestMiddleware(t *testing.T) {
+	store := idempotency.NewStore(time.Minute)
+	defer store.Close()
+
+	var calls atomic.Int64
+	handler := idempotency.Middleware(store, clientScope)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			call := calls.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"call":%d}`, call)
+		},
+	))
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, newRequest("key_1"))
+	if first.Code != http.StatusCreated || first.Body.String() != `{"call":1}` {
+		t.Fatalf("expected first call to run the handler, got %d '%s'", first.Code, first.Body.String())
+	}
+
+	replayed := httptest.NewRecorder()
+	handler.ServeHTTP(replayed, newRequest("key_1"))
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != `{"call":1}` {
+		t.Errorf("expected stored response to be replayed, got %d '%s'", replayed.Code, replayed.Body.String())
+	}
+	if replayed.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected stored headers to be replayed, got %v", replayed.Header())
+	}
+	if replayed.Header().Get(idempotency.ReplayedHeader) != "true" {
+		t.Errorf("expected replayed response to be flagged")
+	}
+
+	other := httptest.NewRecorder()
+	handler.ServeHTTP(other, newRequest("key_2"))
+	if other.Body.String() != `{"call":2}` {
+		t.Errorf("expected other keys to run the handler, got '%s'", other.Body.String())
+	}
+
+	otherClientReq := newRequest("key_1")
+	otherClientReq.Header.Set("X-Client", "client_2")
+	otherClient := httptest.NewRecorder()
+	handler.ServeHTTP(otherClient, otherClientReq)
+	if otherClient.Body.String() != `{"call":3}` {
+		t.Errorf("expected keys to be scoped by client, got '%s'", otherClient.Body.String())
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest(""))
+	if calls.Load() != 4 {
+		t.Errorf("expected requests without key to run the handler, got %d calls", calls.Load())
+	}
+}
+
+func TestMiddlewareConcurrentDuplicate(t *testing.T) {
+	store := idempotency.NewStore(time.Minute)
+	defer store.Close()
+
+	reached := make(chan struct{})
+	release := make(chan struct{})
+	handler := idempotency.Middleware(store, clientScope)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			close(reached)
+			<-release
+		},
+	))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("key_1"))
+	}()
+	<-reached
+
+	duplicate := httptest.NewRecorder()
+	handler.ServeHTTP(duplicate, newRequest("key_1"))
+	if duplicate.Code != http.StatusConflict {
+		t.Errorf("expected status code %d for in-flight duplicate, got %d", http.StatusConflict, duplicate.Code)
+	}
+
+	close(release)
+	<-done
+}
+
+func TestMiddlewareServerError(t *testing.T) {
+	store := idempotency.NewStore(time.Minute)
+	defer store.Close()
+
+	var calls atomic.Int64
+	handler := idempotency.Middleware(store, clientScope)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		},
+	))
+
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("key_1"))
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("key_1"))
+
+	if calls.Load() != 2 {
+		t.Errorf("expected server errors not to be stored, got %d calls", calls.Load())
+	}
+}
+
+func TestMiddlewareInsideCompression(t *testing.T) {
+	store := idempotency.NewStore(time.Minute)
+	defer store.Close()
+
+	const body = `{"message":"created"}`
+	handler := compress.Middleware(5)(idempotency.Middleware(store, clientScope)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(body))
+		},
+	)))
+
+	gzipReq := newRequest("key_1")
+	gzipReq.Header.Set("Accept-Encoding", "gzip")
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, gzipReq)
+
+	if encoding := first.Header().Get("Content-Encoding"); encoding != "gzip" {
+		t.Fatalf("expected first response to be gzip encoded, got '%s'", encoding)
+	}
+	reader, err := gzip.NewReader(first.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded, _ := io.ReadAll(reader)
+	if string(decoded) != body {
+		t.Errorf("expected decoded body '%s', got '%s'", body, decoded)
+	}
+
+	replayed := httptest.NewRecorder()
+	handler.ServeHTTP(replayed, newRequest("key_1"))
+
+	if replayed.Header().Get(idempotency.ReplayedHeader) != "true" {
+		t.Errorf("expected response to be replayed")
+	}
+	if encoding := replayed.Header().Get("Content-Encoding"); encoding != "" {
+		t.Errorf("expected replay without Accept-Encoding not to be encoded, got '%s'", encoding)
+	}
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != body {
+		t.Errorf("expected plain replayed response, got %d '%s'", replayed.Code, replayed.Body.String())
+	}
+
+	gzipReplayReq := newRequest("key_1")
+	gzipReplayReq.Header.Set("Accept-Encoding", "gzip")
+	gzipReplayed := httptest.NewRecorder()
+	handler.ServeHTTP(gzipReplayed, gzipReplayReq)
+
+	if encoding := gzipReplayed.Header().Get("Content-Encoding"); encoding != "gzip" {
+		t.Errorf("expected replay with Accept-Encoding to be gzip encoded, got '%s'", encoding)
+	}
+}
+
+func TestMiddlewareTimeout(t *testing.T) {
+	store := idempotency.NewStore(time.Minute)
+	defer store.Close()
+
+	var calls atomic.Int64
+	handler := timeout.Middleware(20 * time.Millisecond)(idempotency.Middleware(store, clientScope)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			// The first call outlives the timeout without writing anything
+			if calls.Add(1) == 1 {
+				<-r.Context().Done()
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		},
+	)))
+
+	timedOut := httptest.NewRecorder()
+	handler.ServeHTTP(timedOut, newRequest("key_1"))
+	if timedOut.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status code %d, got %d", http.StatusServiceUnavailable, timedOut.Code)
+	}
+
+	// The timed out handler releases the key once it returns
+	var retried *httptest.ResponseRecorder
+	deadline := time.Now().Add(time.Second)
+	for retried == nil || retried.Code == http.StatusConflict && time.Now().Before(deadline) {
+		retried = httptest.NewRecorder()
+		handler.ServeHTTP(retried, newRequest("key_1"))
+	}
+	if retried.Code != http.StatusCreated || retried.Header().Get(idempotency.ReplayedHeader) != "" {
+		t.Errorf("expected retry after a timeout to run the handler, got %d", retried.Code)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected handler to run twice, got %d calls", calls.Load())
+	}
+}
+
+func TestMiddlewareDifferentPayload(t *testing.T) {
+	store := idempotency.NewStore(time.Minute)
+	defer store.Close()
+
+	var calls atomic.Int64
+	handler := idempotency.Middleware(store, clientScope)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			body, _ := io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		},
+	))
+
+	newPayloadRequest := func(payload string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(payload))
+		req.Header.Set(idempotency.KeyHeader, "key_1")
+
+		return req
+	}
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, newPayloadRequest(`{"job":1}`))
+	if first.Body.String() != `{"job":1}` {
+		t.Fatalf("expected the handler to read the request body, got '%s'", first.Body.String())
+	}
+
+	replayed := httptest.NewRecorder()
+	handler.ServeHTTP(replayed, newPayloadRequest(`{"job":1}`))
+	if replayed.Header().Get(idempotency.ReplayedHeader) != "true" {
+		t.Errorf("expected the same payload to be replayed")
+	}
+
+	different := httptest.NewRecorder()
+	handler.ServeHTTP(different, newPayloadRequest(`{"job":2}`))
+	if different.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, different.Code)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected handler to run once, got %d calls", calls.Load())
+	}
+}
+
+func TestStoreMaxResponses(t *testing.T) {
+	store := idempotency.NewStore(time.Minute, idempotency.StoreWithMaxResponses(1))
+	defer store.Close()
+
+	var calls atomic.Int64
+	handler := idempotency.Middleware(store, clientScope)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusCreated)
+		},
+	))
+
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("key_1"))
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("key_2"))
+
+	// The response of the least recently used key was evicted to make room
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, newRequest("key_1"))
+	if res.Header().Get(idempotency.ReplayedHeader) != "" || calls.Load() != 3 {
+		t.Errorf("expected the evicted key to run the handler, got %d calls", calls.Load())
+	}
+}
@@ -0,0 +1,58 @@
+package inflight
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// Counter of the requests being processed
+type Counter struct {
+	count atomic.Int64
+}
+
+// Creates a new counter
+func NewCounter() *Counter {
+	return &Counter{}
+}
+
+// Returns the number of requests being processed
+func (c *Counter) Count() int64 {
+	return c.count.Load()
+}
+
+// Middleware that keeps track of the requests being processed
+func Middleware(
+	counter *Counter,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				counter.count.Add(1)
+				defer counter.count.Add(-1)
+
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
+
+// Handler that exposes the number of requests being processed
+func Handler(
+	counter *Counter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.Marshal(struct {
+			InFlight int64 `json:"in_flight"`
+		}{
+			InFlight: counter.Count(),
+		})
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
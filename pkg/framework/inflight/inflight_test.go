@@ -0,0 +1,86 @@
+package inflight_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/inflight"
+)
+
+func TestMiddleware(t *testing.T) {
+	counter := inflight.NewCounter()
+	release := make(chan struct{})
+	handler := inflight.Middleware(counter)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		},
+	))
+
+	requestCount := 5
+	var wg sync.WaitGroup
+	for i := 0; i < requestCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		}()
+	}
+
+	if !waitForCount(counter, int64(requestCount)) {
+		t.Errorf("expected %d requests in flight, got %d", requestCount, counter.Count())
+	}
+
+	res := httptest.NewRecorder()
+	inflight.Handler(counter).ServeHTTP(res, httptest.NewRequest("GET", "/debug/inflight", nil))
+
+	var body struct {
+		InFlight int64 `json:"in_flight"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unexpected error decoding response: %v", err)
+	}
+	if body.InFlight != int64(requestCount) {
+		t.Errorf("expected exposed count to be %d, got %d", requestCount, body.InFlight)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if counter.Count() != 0 {
+		t.Errorf("expected no requests in flight, got %d", counter.Count())
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	counter := inflight.NewCounter()
+	handler := inflight.Middleware(counter)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			panic("handler panic")
+		},
+	))
+
+	func() {
+		defer func() { recover() }()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+
+	if counter.Count() != 0 {
+		t.Errorf("expected no requests in flight after a panic, got %d", counter.Count())
+	}
+}
+
+func waitForCount(counter *inflight.Counter, count int64) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if counter.Count() == count {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	return false
+}
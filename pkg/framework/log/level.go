@@ -0,0 +1,101 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// Handler exposing the level it filters records by
+type levelVarHandler interface {
+	levelVar() *slog.LevelVar
+}
+
+// Handler that filters records by a level adjustable at runtime
+type levelHandler struct {
+	Handler
+	level *slog.LevelVar
+}
+
+func newLevelHandler(handler Handler, level Level) *levelHandler {
+	levelVar := &slog.LevelVar{}
+	levelVar.Set(level)
+
+	return &levelHandler{
+		Handler: handler,
+		level:   levelVar,
+	}
+}
+
+func (h *levelHandler) levelVar() *slog.LevelVar {
+	return h.level
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level Level) bool {
+	return level >= h.level.Level() && h.Handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{
+		Handler: h.Handler.WithAttrs(attrs),
+		level:   h.level,
+	}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{
+		Handler: h.Handler.WithGroup(name),
+		level:   h.level,
+	}
+}
+
+// Returns the minimum level of the logged records
+func (l *Logger) Level() Level {
+	return l.level.Level()
+}
+
+// Sets the minimum level of the logged records
+//
+// Handlers not built by this package can only be made stricter than their own level
+func (l *Logger) SetLevel(level Level) {
+	l.level.Set(level)
+}
+
+// Handler that exposes and updates the logger level
+func LevelHandler(
+	logger *Logger,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var payload struct {
+				Level *Level `json:"level"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Level == nil {
+				http.Error(w, "Invalid level", http.StatusBadRequest)
+				return
+			}
+
+			logger.SetLevel(*payload.Level)
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := json.Marshal(struct {
+			Level string `json:"level"`
+		}{
+			Level: logger.Level().String(),
+		})
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
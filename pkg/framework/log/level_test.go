@@ -0,0 +1,79 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/log"
+)
+
+func TestLoggerSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger("producer", log.NewHandler(&buf, "prod"))
+
+	logger.Debug("hidden message", nil)
+	if buf.Len() != 0 {
+		t.Fatalf("expected debug message to be discarded in prod, got '%s'", buf.String())
+	}
+
+	logger.SetLevel(log.LevelDebug)
+	logger.With(map[string]any{"request_id": "request_1"}).Debug("debug message", nil)
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 1 || entries[0][log.MessageKey] != "debug message" {
+		t.Errorf("expected debug message to be emitted after lowering the level, got %v", entries)
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger("producer", log.NewHandler(&buf, "prod"))
+	handler := log.LevelHandler(logger)
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/debug/log-level", nil))
+	if level := decodeLevel(t, res); level != "INFO" {
+		t.Errorf("expected level 'INFO', got '%s'", level)
+	}
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodPut, "/debug/log-level", strings.NewReader(`{"level":"DEBUG"}`)))
+	if level := decodeLevel(t, res); level != "DEBUG" {
+		t.Errorf("expected level 'DEBUG', got '%s'", level)
+	}
+
+	logger.Debug("debug message", nil)
+	if !strings.Contains(buf.String(), "debug message") {
+		t.Errorf("expected debug message to be emitted, got '%s'", buf.String())
+	}
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodPut, "/debug/log-level", strings.NewReader(`{"level":"LOUD"}`)))
+	if res.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, res.Code)
+	}
+	if logger.Level() != log.LevelDebug {
+		t.Errorf("expected level to remain 'DEBUG', got '%s'", logger.Level())
+	}
+}
+
+func decodeLevel(t *testing.T, res *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, res.Code)
+	}
+
+	var body struct {
+		Level string `json:"level"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("unexpected error decoding response: %v", err)
+	}
+
+	return body.Level
+}
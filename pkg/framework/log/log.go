@@ -5,53 +5,155 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"slices"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Logger struct {
 	producerName string
 	logger       *slog.Logger
+	level        *slog.LevelVar
+	flatContext  bool
+}
+
+type LoggerOption func(*Logger)
+
+// Logger option to merge map contexts into top-level attributes
+func WithFlatContext() LoggerOption {
+	return func(l *Logger) {
+		l.flatContext = true
+	}
 }
 
 type Handler = slog.Handler
 
 type Level = slog.Level
 
+const (
+	LevelDebug = slog.LevelDebug
+	LevelInfo  = slog.LevelInfo
+	LevelWarn  = slog.LevelWarn
+	LevelError = slog.LevelError
+)
+
 func NewLogger(
 	producerName string,
 	handler Handler,
+	options ...LoggerOption,
 ) *Logger {
-	return &Logger{
+	var level *slog.LevelVar
+	if leveledHandler, ok := handler.(levelVarHandler); ok {
+		level = leveledHandler.levelVar()
+	}
+	if level == nil {
+		leveledHandler := newLevelHandler(handler, slog.LevelDebug)
+		handler = leveledHandler
+		level = leveledHandler.level
+	}
+
+	l := &Logger{
 		producerName: producerName,
 		logger:       slog.New(handler),
+		level:        level,
+	}
+	for _, option := range options {
+		option(l)
+	}
+
+	return l
+}
+
+// Returns a child logger adding the given fields to every log entry
+func (l *Logger) With(fields map[string]any) *Logger {
+	attrs := make([]any, 0, len(fields))
+	for _, attr := range fieldAttrs(fields) {
+		attrs = append(attrs, attr)
+	}
+
+	return &Logger{
+		producerName: l.producerName,
+		logger:       l.logger.With(attrs...),
+		level:        l.level,
+		flatContext:  l.flatContext,
 	}
 }
 
 func (l *Logger) Debug(msg string, ctx any) {
-	l.log(msg, ctx, slog.LevelDebug)
+	l.log(context.Background(), msg, ctx, slog.LevelDebug)
 }
 
 func (l *Logger) Info(msg string, ctx any) {
-	l.log(msg, ctx, slog.LevelInfo)
+	l.log(context.Background(), msg, ctx, slog.LevelInfo)
 }
 
 func (l *Logger) Warn(msg string, ctx any) {
-	l.log(msg, ctx, slog.LevelWarn)
+	l.log(context.Background(), msg, ctx, slog.LevelWarn)
 }
 
 func (l *Logger) Error(msg string, ctx any) {
-	l.log(msg, ctx, slog.LevelError)
+	l.log(context.Background(), msg, ctx, slog.LevelError)
+}
+
+// Logs at debug level with the trace of the given context
+func (l *Logger) DebugContext(ctx context.Context, msg string, logCtx any) {
+	l.log(ctx, msg, logCtx, slog.LevelDebug)
+}
+
+// Logs at info level with the trace of the given context
+func (l *Logger) InfoContext(ctx context.Context, msg string, logCtx any) {
+	l.log(ctx, msg, logCtx, slog.LevelInfo)
 }
 
-func (l *Logger) log(msg string, ctx any, lvl Level) {
+// Logs at warn level with the trace of the given context
+func (l *Logger) WarnContext(ctx context.Context, msg string, logCtx any) {
+	l.log(ctx, msg, logCtx, slog.LevelWarn)
+}
+
+// Logs at error level with the trace of the given context
+func (l *Logger) ErrorContext(ctx context.Context, msg string, logCtx any) {
+	l.log(ctx, msg, logCtx, slog.LevelError)
+}
+
+func (l *Logger) log(ctx context.Context, msg string, logCtx any, lvl Level) {
+	attrs := []slog.Attr{slog.String(ProducerKey, l.producerName)}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		attrs = append(
+			attrs,
+			slog.String(TraceIDKey, spanCtx.TraceID().String()),
+			slog.String(SpanIDKey, spanCtx.SpanID().String()),
+		)
+	}
+	if fields, ok := logCtx.(map[string]any); ok && l.flatContext {
+		attrs = append(attrs, fieldAttrs(fields)...)
+	} else {
+		attrs = append(attrs, slog.Any(ContextKey, logCtx))
+	}
+
 	l.logger.LogAttrs(
-		context.TODO(),
+		ctx,
 		lvl,
 		msg,
-		slog.String(ProducerKey, l.producerName),
-		slog.Any(ContextKey, ctx),
+		attrs...,
 	)
 }
 
+// Converts the given fields into attributes sorted by key
+func fieldAttrs(fields map[string]any) []slog.Attr {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, key := range keys {
+		attrs = append(attrs, slog.Any(key, fields[key]))
+	}
+
+	return attrs
+}
+
 const (
 	// Default keys
 	LevelKey   = "level"
@@ -62,23 +164,49 @@ const (
 	// Custom keys
 	ProducerKey = "producer"
 	ContextKey  = "context"
+	TraceIDKey  = "trace_id"
+	SpanIDKey   = "span_id"
+)
+
+const (
+	// Handler formats
+	FormatJSON = "json"
+	FormatText = "text"
 )
 
 func NewHandler(
 	w io.Writer,
 	env string,
+) Handler {
+	return NewHandlerWithFormat(w, env, FormatJSON)
+}
+
+func NewHandlerWithFormat(
+	w io.Writer,
+	env string,
+	format string,
 ) Handler {
 	level, err := EnvironmentLevel(env)
 	if err != nil {
 		panic(err)
 	}
 
+	leveledHandler := newLevelHandler(nil, level)
 	options := slog.HandlerOptions{
 		ReplaceAttr: ReplaceAttrs,
-		Level:       level,
+		Level:       leveledHandler.level,
+	}
+
+	switch format {
+	case FormatJSON:
+		leveledHandler.Handler = slog.NewJSONHandler(w, &options)
+	case FormatText:
+		leveledHandler.Handler = slog.NewTextHandler(w, &options)
+	default:
+		panic(fmt.Errorf("unsupported format \"%s\"", format))
 	}
 
-	return slog.NewJSONHandler(w, &options)
+	return leveledHandler
 }
 
 func EnvironmentLevel(env string) (Level, error) {
@@ -0,0 +1,169 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func decodeEntries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("unexpected error decoding log entry '%s': %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestLoggerWith(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger("producer", log.NewHandler(&buf, "dev"))
+
+	childLogger := logger.With(map[string]any{
+		"request_id": "request_1",
+		"trace_id":   "trace_1",
+	})
+
+	childLogger.Debug("debug message", nil)
+	childLogger.Info("info message", nil)
+	childLogger.Warn("warn message", nil)
+	childLogger.Error("error message", nil)
+	logger.Info("parent message", nil)
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 log entries, got %d", len(entries))
+	}
+
+	for _, entry := range entries[:4] {
+		if entry["request_id"] != "request_1" || entry["trace_id"] != "trace_1" {
+			t.Errorf("expected entry to carry the child logger fields, got %v", entry)
+		}
+		if entry[log.ProducerKey] != "producer" {
+			t.Errorf("expected entry producer to be 'producer', got %v", entry[log.ProducerKey])
+		}
+	}
+
+	if _, found := entries[4]["request_id"]; found {
+		t.Errorf("expected parent logger entries not to carry the child logger fields")
+	}
+}
+
+func TestLoggerWithFlatContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger("producer", log.NewHandler(&buf, "dev"), log.WithFlatContext())
+
+	logger.Info("map message", map[string]any{"message_id": "message_1"})
+	logger.Info("struct message", struct {
+		MessageID string `json:"message_id"`
+	}{MessageID: "message_2"})
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 log entries, got %d", len(entries))
+	}
+
+	if entries[0]["message_id"] != "message_1" {
+		t.Errorf("expected top-level message_id 'message_1', got %v", entries[0]["message_id"])
+	}
+	if _, found := entries[0][log.ContextKey]; found {
+		t.Errorf("expected map context not to be nested under '%s'", log.ContextKey)
+	}
+
+	logCtx, ok := entries[1][log.ContextKey].(map[string]any)
+	if !ok || logCtx["message_id"] != "message_2" {
+		t.Errorf("expected struct context to be nested under '%s', got %v", log.ContextKey, entries[1])
+	}
+}
+
+func TestLoggerNestedContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger("producer", log.NewHandler(&buf, "dev"))
+
+	logger.Info("map message", map[string]any{"message_id": "message_1"})
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 log entry, got %d", len(entries))
+	}
+
+	logCtx, ok := entries[0][log.ContextKey].(map[string]any)
+	if !ok || logCtx["message_id"] != "message_1" {
+		t.Errorf("expected map context to be nested under '%s', got %v", log.ContextKey, entries[0])
+	}
+}
+
+func TestNewHandlerWithTextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger("producer", log.NewHandlerWithFormat(&buf, "dev", log.FormatText))
+
+	logger.Info("hello world", nil)
+
+	output := buf.String()
+	expectedParts := []string{
+		log.LevelKey + "=INFO",
+		log.MessageKey + "=\"hello world\"",
+		log.ProducerKey + "=producer",
+	}
+	for _, part := range expectedParts {
+		if !strings.Contains(output, part) {
+			t.Errorf("expected text output to contain '%s', got '%s'", part, output)
+		}
+	}
+}
+
+func TestNewHandlerWithUnsupportedFormat(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected unsupported format to panic")
+		}
+	}()
+
+	log.NewHandlerWithFormat(&bytes.Buffer{}, "dev", "xml")
+}
+
+func TestLoggerContextTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger("producer", log.NewHandler(&buf, "dev"))
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	logger.InfoContext(ctx, "traced message", nil)
+	logger.InfoContext(context.Background(), "untraced message", nil)
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 log entries, got %d", len(entries))
+	}
+
+	if entries[0][log.TraceIDKey] != traceID.String() {
+		t.Errorf("expected trace ID '%s', got %v", traceID, entries[0][log.TraceIDKey])
+	}
+	if entries[0][log.SpanIDKey] != spanID.String() {
+		t.Errorf("expected span ID '%s', got %v", spanID, entries[0][log.SpanIDKey])
+	}
+	if _, found := entries[1][log.TraceIDKey]; found {
+		t.Errorf("expected entry without span not to carry a trace ID")
+	}
+}
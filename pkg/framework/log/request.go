@@ -0,0 +1,60 @@
+package log
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+type ctxKey uint
+
+const (
+	loggerCtxKey ctxKey = iota
+)
+
+const (
+	// Request keys
+	RequestIDKey = "request_id"
+)
+
+// Middleware that stores a logger seeded with the request ID in the request's context
+func RequestLoggerMiddleware(
+	logger *Logger,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				requestLogger := logger
+				if requestID := middleware.GetReqID(r.Context()); requestID != "" {
+					requestLogger = logger.With(map[string]any{
+						RequestIDKey: requestID,
+					})
+				}
+
+				next.ServeHTTP(w, RequestWithLogger(r, requestLogger))
+			},
+		)
+	}
+}
+
+// Returns a shallow copy of the request with the given logger in its context
+func RequestWithLogger(r *http.Request, logger *Logger) *http.Request {
+	return r.WithContext(
+		context.WithValue(
+			r.Context(),
+			loggerCtxKey,
+			logger,
+		),
+	)
+}
+
+// Extracts the logger from the given request's context
+func FromRequest(r *http.Request) (*Logger, bool) {
+	logger, valid := r.Context().Value(loggerCtxKey).(*Logger)
+	if !valid {
+		return nil, false
+	}
+
+	return logger, true
+}
@@ -0,0 +1,45 @@
+package log_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/sergioneiravargas/template-go/pkg/framework/log"
+)
+
+func TestRequestLoggerMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger("producer", log.NewHandler(&buf, "dev"))
+
+	var requestID string
+	handler := middleware.RequestID(log.RequestLoggerMiddleware(logger)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requestID = middleware.GetReqID(r.Context())
+
+			requestLogger, found := log.FromRequest(r)
+			if !found {
+				t.Fatalf("expected logger to be found in the request's context")
+			}
+			requestLogger.Info("handler message", nil)
+		},
+	)))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 log entry, got %d", len(entries))
+	}
+	if requestID == "" || entries[0][log.RequestIDKey] != requestID {
+		t.Errorf("expected request ID '%s', got %v", requestID, entries[0][log.RequestIDKey])
+	}
+}
+
+func TestFromRequestWithoutLogger(t *testing.T) {
+	if _, found := log.FromRequest(httptest.NewRequest(http.MethodGet, "/", nil)); found {
+		t.Errorf("expected logger not to be found in the request's context")
+	}
+}
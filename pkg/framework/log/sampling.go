@@ -0,0 +1,88 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// Window in which identical messages are sampled
+	DefaultSamplingWindow = time.Second
+)
+
+// Creates a handler that only emits 1 of every n identical messages within a window
+//
+// Records at error level or above are never sampled out
+func NewSamplingHandler(inner Handler, n int) Handler {
+	return &samplingHandler{
+		Handler: inner,
+		sampler: &sampler{
+			n:      uint64(max(n, 1)),
+			window: DefaultSamplingWindow,
+			counts: make(map[string]uint64),
+		},
+	}
+}
+
+// Handler that samples records by message
+type samplingHandler struct {
+	Handler
+	sampler *sampler
+}
+
+func (h *samplingHandler) levelVar() *slog.LevelVar {
+	if leveledHandler, ok := h.Handler.(levelVarHandler); ok {
+		return leveledHandler.levelVar()
+	}
+
+	return nil
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelError && !h.sampler.sample(r.Message, r.Time) {
+		return nil
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{
+		Handler: h.Handler.WithAttrs(attrs),
+		sampler: h.sampler,
+	}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{
+		Handler: h.Handler.WithGroup(name),
+		sampler: h.sampler,
+	}
+}
+
+// Message counter shared by a sampling handler and its children
+type sampler struct {
+	mu          sync.Mutex
+	n           uint64
+	window      time.Duration
+	windowStart time.Time
+	counts      map[string]uint64
+}
+
+// Reports whether a message logged at the given time should be emitted
+func (s *sampler) sample(msg string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.windowStart) >= s.window || now.Before(s.windowStart) {
+		s.windowStart = now
+		clear(s.counts)
+	}
+
+	count := s.counts[msg]
+	s.counts[msg] = count + 1
+
+	return count%s.n == 0
+}
@@ -0,0 +1,46 @@
+package log_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/log"
+)
+
+func TestSamplingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger("producer", log.NewSamplingHandler(log.NewHandler(&buf, "dev"), 5))
+
+	for i := 0; i < 10; i++ {
+		logger.Info("message processed", nil)
+		logger.Error("message failed", nil)
+	}
+	logger.Info("other message", nil)
+
+	counts := map[string]int{}
+	for _, entry := range decodeEntries(t, &buf) {
+		counts[entry[log.MessageKey].(string)]++
+	}
+
+	if counts["message processed"] != 2 {
+		t.Errorf("expected 2 sampled info messages, got %d", counts["message processed"])
+	}
+	if counts["message failed"] != 10 {
+		t.Errorf("expected 10 error messages, got %d", counts["message failed"])
+	}
+	if counts["other message"] != 1 {
+		t.Errorf("expected 1 other message, got %d", counts["other message"])
+	}
+}
+
+func TestSamplingHandlerSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger("producer", log.NewSamplingHandler(log.NewHandler(&buf, "prod"), 5))
+
+	logger.SetLevel(log.LevelDebug)
+	logger.Debug("debug message", nil)
+
+	if entries := decodeEntries(t, &buf); len(entries) != 1 {
+		t.Errorf("expected debug message to be emitted after lowering the level, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Creates a logger that discards every log entry
+func NewNopLogger() *Logger {
+	return NewLogger("", nopHandler{})
+}
+
+// Handler that discards every record
+type nopHandler struct{}
+
+func (nopHandler) Enabled(context.Context, Level) bool {
+	return false
+}
+
+func (nopHandler) Handle(context.Context, slog.Record) error {
+	return nil
+}
+
+func (h nopHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+func (h nopHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// Creates a logger that captures every log entry in the returned records
+func NewTestLogger() (*Logger, *Records) {
+	records := &Records{}
+
+	return NewLogger("test", &recordsHandler{records: records}), records
+}
+
+// Log entry captured by a test logger
+type Record struct {
+	Level   Level
+	Message string
+	Fields  map[string]any
+}
+
+// Log entries captured by a test logger
+type Records struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// Returns the captured log entries
+func (r *Records) All() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Record(nil), r.records...)
+}
+
+// Returns the captured log entries with the given message
+func (r *Records) WithMessage(msg string) []Record {
+	var records []Record
+	for _, record := range r.All() {
+		if record.Message == msg {
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
+
+// Removes the captured log entries
+func (r *Records) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records = nil
+}
+
+func (r *Records) add(record Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records = append(r.records, record)
+}
+
+// Handler that captures records for test assertions
+type recordsHandler struct {
+	records *Records
+	attrs   []slog.Attr
+}
+
+func (h *recordsHandler) Enabled(context.Context, Level) bool {
+	return true
+}
+
+func (h *recordsHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := make(map[string]any, len(h.attrs)+r.NumAttrs())
+	for _, attr := range h.attrs {
+		fields[attr.Key] = attr.Value.Resolve().Any()
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		fields[attr.Key] = attr.Value.Resolve().Any()
+		return true
+	})
+
+	h.records.add(Record{
+		Level:   r.Level,
+		Message: r.Message,
+		Fields:  fields,
+	})
+
+	return nil
+}
+
+func (h *recordsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &recordsHandler{
+		records: h.records,
+		attrs:   append(append([]slog.Attr(nil), h.attrs...), attrs...),
+	}
+}
+
+func (h *recordsHandler) WithGroup(string) slog.Handler {
+	return h
+}
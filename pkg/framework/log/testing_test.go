@@ -0,0 +1,49 @@
+package log_test
+
+import (
+	"testing"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/log"
+)
+
+func TestNewTestLogger(t *testing.T) {
+	logger, records := log.NewTestLogger()
+
+	logger.With(map[string]any{"request_id": "request_1"}).Info("message processed", map[string]any{
+		"message_id": "message_1",
+	})
+	logger.Debug("other message", nil)
+
+	processed := records.WithMessage("message processed")
+	if len(processed) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(processed))
+	}
+
+	record := processed[0]
+	if record.Level != log.LevelInfo {
+		t.Errorf("expected level '%s', got '%s'", log.LevelInfo, record.Level)
+	}
+	if record.Fields["request_id"] != "request_1" {
+		t.Errorf("expected request_id 'request_1', got %v", record.Fields["request_id"])
+	}
+	logCtx, ok := record.Fields[log.ContextKey].(map[string]any)
+	if !ok || logCtx["message_id"] != "message_1" {
+		t.Errorf("expected context message_id 'message_1', got %v", record.Fields[log.ContextKey])
+	}
+
+	if len(records.All()) != 2 {
+		t.Errorf("expected 2 records, got %d", len(records.All()))
+	}
+
+	records.Reset()
+	if len(records.All()) != 0 {
+		t.Errorf("expected no records after reset, got %d", len(records.All()))
+	}
+}
+
+func TestNewNopLogger(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	logger.Info("discarded message", nil)
+	logger.With(map[string]any{"request_id": "request_1"}).Error("discarded message", nil)
+}
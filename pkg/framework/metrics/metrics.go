@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// Metric names
+	HTTPRequestDurationName = "http_request_duration_seconds"
+)
+
+// Registry of the application collectors
+type Metrics struct {
+	registry            *prometheus.Registry
+	httpRequestDuration *prometheus.HistogramVec
+}
+
+// Creates a registry with the runtime and HTTP collectors
+func New() *Metrics {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	httpRequestDuration := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    HTTPRequestDurationName,
+			Help:    "Duration of the HTTP requests by method, route and status code",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "route", "status"},
+	)
+	registry.MustRegister(httpRequestDuration)
+
+	return &Metrics{
+		registry:            registry,
+		httpRequestDuration: httpRequestDuration,
+	}
+}
+
+// Registers additional collectors
+func (m *Metrics) Register(collectors ...prometheus.Collector) error {
+	for _, collector := range collectors {
+		if err := m.registry.Register(collector); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Handler that exposes the registered collectors
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware that observes the duration of the requests
+func Middleware(
+	metrics *Metrics,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				start := time.Now()
+				ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+				next.ServeHTTP(ww, r)
+
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+
+				metrics.httpRequestDuration.WithLabelValues(
+					r.Method,
+					routePattern(r),
+					strconv.Itoa(status),
+				).Observe(time.Since(start).Seconds())
+			},
+		)
+	}
+}
+
+// Returns the matched route pattern to keep the label cardinality bounded
+func routePattern(r *http.Request) string {
+	routeCtx := chi.RouteContext(r.Context())
+	if routeCtx == nil || routeCtx.RoutePattern() == "" {
+		return "unmatched"
+	}
+
+	return routeCtx.RoutePattern()
+}
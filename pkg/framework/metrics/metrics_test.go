@@ -0,0 +1,42 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sergioneiravargas/template-go/pkg/framework/metrics"
+)
+
+func TestMiddleware(t *testing.T) {
+	m := metrics.New()
+
+	r := chi.NewRouter()
+	r.Use(metrics.Middleware(m))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	r.Handle("/metrics", m.Handler())
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, res.Code)
+	}
+
+	body, _ := io.ReadAll(res.Body)
+	expectedParts := []string{
+		metrics.HTTPRequestDurationName + `_count{method="GET",route="/users/{id}",status="201"} 1`,
+		"go_goroutines",
+	}
+	for _, part := range expectedParts {
+		if !strings.Contains(string(body), part) {
+			t.Errorf("expected metrics to contain '%s'", part)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/cache"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// Time a per-key limiter is kept after the last event for its key
+	DefaultLimiterTTL = 10 * time.Minute
+	// Number of per-key limiters kept, evicting the least recently used one
+	DefaultMaxKeys = 100_000
+)
+
+type LimiterOption func(*limiterOptions)
+
+type limiterOptions struct {
+	ttl     time.Duration
+	maxKeys int
+}
+
+// Sets the time a per-key limiter is kept after the last event for its key
+func LimiterWithTTL(ttl time.Duration) LimiterOption {
+	return func(o *limiterOptions) {
+		o.ttl = ttl
+	}
+}
+
+// Sets the number of per-key limiters kept, bounding the memory used by the
+// limiter regardless of the number of distinct keys
+func LimiterWithMaxKeys(maxKeys int) LimiterOption {
+	return func(o *limiterOptions) {
+		o.maxKeys = maxKeys
+	}
+}
+
+// Token bucket limiters by key
+type Limiter struct {
+	limit    rate.Limit
+	burst    int
+	limiters *cache.Cache[string, *rate.Limiter]
+}
+
+// Creates a limiter allowing limit events per second with the given burst for each key
+func NewLimiter(
+	limit rate.Limit,
+	burst int,
+	opts ...LimiterOption,
+) *Limiter {
+	options := limiterOptions{
+		ttl:     DefaultLimiterTTL,
+		maxKeys: DefaultMaxKeys,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &Limiter{
+		limit: limit,
+		burst: burst,
+		limiters: cache.New[string, *rate.Limiter](
+			cache.WithTTL[string, *rate.Limiter](options.ttl),
+			cache.WithMaxSize[string, *rate.Limiter](options.maxKeys),
+		),
+	}
+}
+
+// Reports whether an event for the given key is allowed, and otherwise how
+// long to wait before retrying
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	limiter, _ := l.limiters.GetOrSet(key, func() (*rate.Limiter, error) {
+		return rate.NewLimiter(l.limit, l.burst), nil
+	})
+	// Keep the limiter while its key is active so the bucket isn't reset
+	l.limiters.Set(key, limiter)
+
+	reservation := limiter.Reserve()
+	if !reservation.OK() {
+		return false, 0
+	}
+
+	delay := reservation.Delay()
+	if delay > 0 {
+		reservation.Cancel()
+		return false, delay
+	}
+
+	return true, 0
+}
+
+// Stops the eviction of the per-key limiters
+func (l *Limiter) Close() {
+	l.limiters.Close()
+}
+
+// Middleware that rejects the requests exceeding the rate of their key
+func Middleware(
+	limit rate.Limit,
+	burst int,
+	keyFn func(*http.Request) string,
+) func(next http.Handler) http.Handler {
+	return LimiterMiddleware(NewLimiter(limit, burst), keyFn)
+}
+
+// Middleware that rejects the requests exceeding the rate of their key
+// using the given limiter
+func LimiterMiddleware(
+	limiter *Limiter,
+	keyFn func(*http.Request) string,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				allowed, retryAfter := limiter.Allow(keyFn(r))
+				if !allowed {
+					if retryAfter > 0 {
+						w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+					}
+					http.Error(w, "Too many requests", http.StatusTooManyRequests)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
+
+type ctxKey uint
+
+const (
+	connectionAddrCtxKey ctxKey = iota
+)
+
+// Middleware that keeps the address of the connection in the request's context
+// before it's overwritten from the forwarding headers sent by the client (e.g.
+// by middleware.RealIP), it must be mounted before the middlewares doing so
+func ConnectionAddrMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, RequestWithConnectionAddr(r, r.RemoteAddr))
+		},
+	)
+}
+
+// Returns a shallow copy of the request with the given connection address in its context
+func RequestWithConnectionAddr(r *http.Request, addr string) *http.Request {
+	return r.WithContext(
+		context.WithValue(
+			r.Context(),
+			connectionAddrCtxKey,
+			addr,
+		),
+	)
+}
+
+// Retrieves the connection address from the request's context
+func ConnectionAddrFromRequest(r *http.Request) (string, bool) {
+	addr, found := r.Context().Value(connectionAddrCtxKey).(string)
+
+	return addr, found
+}
+
+// Returns the IP of the connection as the rate limit key, the forwarding
+// headers are ignored as clients could send a different value on each request
+func IPKey(r *http.Request) string {
+	addr, found := ConnectionAddrFromRequest(r)
+	if !found {
+		addr = r.RemoteAddr
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
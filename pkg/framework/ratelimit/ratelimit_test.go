@@ -0,0 +1,126 @@
+package ratelimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/ratelimit"
+
+	"github.com/go-chi/chi/middleware"
+	"golang.org/x/time/rate"
+)
+
+func TestLimiterMiddleware(t *testing.T) {
+	limiter := ratelimit.NewLimiter(rate.Every(100*time.Millisecond), 2)
+	defer limiter.Close()
+
+	handler := ratelimit.LimiterMiddleware(limiter, ratelimit.IPKey)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {},
+	))
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		return res
+	}
+
+	for i := 0; i < 2; i++ {
+		if res := serve("10.0.0.1:1234"); res.Code != http.StatusOK {
+			t.Errorf("expected status code %d within the burst, got %d", http.StatusOK, res.Code)
+		}
+	}
+
+	res := serve("10.0.0.1:5678")
+	if res.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status code %d beyond the burst, got %d", http.StatusTooManyRequests, res.Code)
+	}
+	if retryAfter := res.Header().Get("Retry-After"); retryAfter != "1" {
+		t.Errorf("expected Retry-After '1', got '%s'", retryAfter)
+	}
+
+	if res := serve("10.0.0.2:1234"); res.Code != http.StatusOK {
+		t.Errorf("expected other keys not to be limited, got %d", res.Code)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if res := serve("10.0.0.1:1234"); res.Code != http.StatusOK {
+		t.Errorf("expected status code %d after the window, got %d", http.StatusOK, res.Code)
+	}
+}
+
+func TestIPKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	req.RemoteAddr = "10.0.0.1:1234"
+	if key := ratelimit.IPKey(req); key != "10.0.0.1" {
+		t.Errorf("expected key '10.0.0.1', got '%s'", key)
+	}
+
+	req.RemoteAddr = "10.0.0.1"
+	if key := ratelimit.IPKey(req); key != "10.0.0.1" {
+		t.Errorf("expected key '10.0.0.1', got '%s'", key)
+	}
+}
+
+func TestIPKeyIgnoresForwardingHeaders(t *testing.T) {
+	var key string
+	handler := ratelimit.ConnectionAddrMiddleware(middleware.RealIP(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			key = ratelimit.IPKey(r)
+		},
+	)))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if key != "10.0.0.1" {
+		t.Errorf("expected key '10.0.0.1', got '%s'", key)
+	}
+}
+
+func TestLimiterKeepsActiveKeys(t *testing.T) {
+	limiter := ratelimit.NewLimiter(rate.Every(time.Hour), 1, ratelimit.LimiterWithTTL(100*time.Millisecond))
+	defer limiter.Close()
+
+	if allowed, _ := limiter.Allow("key"); !allowed {
+		t.Fatalf("expected the first event to be allowed")
+	}
+
+	// Keep the key active past the TTL counted from the limiter creation
+	for i := 0; i < 3; i++ {
+		time.Sleep(60 * time.Millisecond)
+		if allowed, _ := limiter.Allow("key"); allowed {
+			t.Errorf("expected the bucket of an active key not to be reset")
+		}
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if allowed, _ := limiter.Allow("key"); !allowed {
+		t.Errorf("expected the limiter of an idle key to expire")
+	}
+}
+
+func TestLimiterMaxKeys(t *testing.T) {
+	limiter := ratelimit.NewLimiter(rate.Every(time.Hour), 1, ratelimit.LimiterWithMaxKeys(1))
+	defer limiter.Close()
+
+	limiter.Allow("first")
+	if allowed, _ := limiter.Allow("first"); allowed {
+		t.Fatalf("expected the burst of the key to be exhausted")
+	}
+
+	// The limiter of the least recently used key is evicted to make room
+	limiter.Allow("second")
+	if allowed, _ := limiter.Allow("first"); !allowed {
+		t.Errorf("expected the evicted key to get a new limiter")
+	}
+}
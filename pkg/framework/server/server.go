@@ -0,0 +1,25 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Shuts the server down gracefully, forcing the remaining connections to
+// close once the timeout elapses
+func Shutdown(
+	ctx context.Context,
+	server *http.Server,
+	timeout time.Duration,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		return errors.Join(err, server.Close())
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/server"
+)
+
+func TestShutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reached := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(reached)
+			<-release
+		}),
+	}
+	go srv.Serve(listener)
+
+	requestErr := make(chan error, 1)
+	go func() {
+		res, err := http.Get("http://" + listener.Addr().String())
+		if err == nil {
+			res.Body.Close()
+		}
+		requestErr <- err
+	}()
+	<-reached
+
+	start := time.Now()
+	err = server.Shutdown(context.Background(), srv, 50*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error '%s', got '%v'", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected shutdown to return after the timeout, took %s", elapsed)
+	}
+
+	select {
+	case err := <-requestErr:
+		if err == nil {
+			t.Errorf("expected slow request to be interrupted")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected slow request connection to be closed")
+	}
+}
+
+func TestShutdownIdle(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := &http.Server{Handler: http.NotFoundHandler()}
+	go srv.Serve(listener)
+
+	if err := server.Shutdown(context.Background(), srv, time.Second); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package sql_test
+
+import (
+	"context"
+	gosql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// In-memory database recording the operations run against it
+type fakeDB struct {
+	mu          sync.Mutex
+	events      []string
+	execErrors  []error
+	rollbackErr error
+
+	// Versions inserted into the schema_migrations table
+	migrations []string
+
+	// Results of the other queries by query
+	queries map[string]func(args []driver.NamedValue) []string
+}
+
+// Opens a connection to a fake database, the given errors are returned by
+// successive Exec calls
+func newFakeDB(execErrors ...error) (*gosql.DB, *fakeDB) {
+	db := &fakeDB{execErrors: execErrors}
+
+	return gosql.OpenDB(db), db
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{db: db}, nil
+}
+
+func (db *fakeDB) Driver() driver.Driver {
+	return fakeDriver{db: db}
+}
+
+func (db *fakeDB) Events() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return append([]string(nil), db.events...)
+}
+
+func (db *fakeDB) record(event string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.events = append(db.events, event)
+}
+
+func (db *fakeDB) nextExecError() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if len(db.execErrors) == 0 {
+		return nil
+	}
+
+	err := db.execErrors[0]
+	db.execErrors = db.execErrors[1:]
+
+	return err
+}
+
+type fakeDriver struct {
+	db *fakeDB
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{db: d.db}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.record("begin")
+
+	return fakeTx{db: c.db}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.record(query)
+	if err := c.db.nextExecError(); err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(query, "INSERT INTO schema_migrations") {
+		c.db.mu.Lock()
+		c.db.migrations = append(c.db.migrations, args[0].Value.(string))
+		c.db.mu.Unlock()
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.record(query)
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	if strings.HasPrefix(query, "SELECT version FROM schema_migrations") {
+		return &fakeRows{values: append([]string(nil), c.db.migrations...)}, nil
+	}
+
+	if results, found := c.db.queries[query]; found {
+		return &fakeRows{values: results(args)}, nil
+	}
+
+	return nil, errors.New("unsupported query")
+}
+
+// Single column rows of string values
+type fakeRows struct {
+	values []string
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"value"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	dest[0] = r.values[0]
+	r.values = r.values[1:]
+
+	return nil
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx fakeTx) Commit() error {
+	tx.db.record("commit")
+
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.record("rollback")
+
+	return tx.db.rollbackErr
+}
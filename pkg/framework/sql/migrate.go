@@ -0,0 +1,118 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+var (
+	ErrMigrationFailed = errors.New("could not apply migration")
+)
+
+const (
+	createMigrationsTableQuery = `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`
+	selectMigrationsQuery = `SELECT version FROM schema_migrations`
+	insertMigrationQuery  = `INSERT INTO schema_migrations (version) VALUES ($1)`
+	lockMigrationsQuery   = `SELECT pg_advisory_lock($1)`
+	unlockMigrationsQuery = `SELECT pg_advisory_unlock($1)`
+)
+
+// Advisory lock held while migrating so that the instances starting at the
+// same time apply the migrations one after another
+const migrationsLockID int64 = 8_317_560_114_277_341_802
+
+// Applies the .sql files of the given directory not applied yet, in filename
+// order, tracking the applied versions in the schema_migrations table. The
+// migrations are serialized with an advisory lock across the database clients
+func Migrate(
+	ctx context.Context,
+	db *DB,
+	migrations fs.FS,
+	dir string,
+) (err error) {
+	entries, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return err
+	}
+
+	// The advisory lock belongs to the session, so it's held on its own connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, lockMigrationsQuery, migrationsLockID); err != nil {
+		return err
+	}
+	defer func() {
+		// Release the lock even if the context is done
+		_, unlockErr := conn.ExecContext(context.WithoutCancel(ctx), unlockMigrationsQuery, migrationsLockID)
+		err = errors.Join(err, unlockErr)
+	}()
+
+	if _, err := db.ExecContext(ctx, createMigrationsTableQuery); err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		version := strings.TrimSuffix(entry.Name(), ".sql")
+		if applied[version] {
+			continue
+		}
+
+		query, err := fs.ReadFile(migrations, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		err = WithTx(ctx, db, func(tx *Tx) error {
+			if _, err := tx.ExecContext(ctx, string(query)); err != nil {
+				return err
+			}
+
+			_, err := tx.ExecContext(ctx, insertMigrationQuery, version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%w \"%s\": %w", ErrMigrationFailed, version, err)
+		}
+	}
+
+	return nil
+}
+
+func appliedMigrations(ctx context.Context, db *DB) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, selectMigrationsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[string]bool{}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
@@ -0,0 +1,67 @@
+package sql_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/sql"
+)
+
+func TestMigrate(t *testing.T) {
+	db, fake := newFakeDB()
+	defer db.Close()
+
+	migrations := fstest.MapFS{
+		"migrations/002_create_b.sql": {Data: []byte("CREATE TABLE b")},
+		"migrations/001_create_a.sql": {Data: []byte("CREATE TABLE a")},
+		"migrations/README.md":        {Data: []byte("Migrations")},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := sql.Migrate(context.Background(), db, migrations, "migrations"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	var applied []string
+	for _, event := range fake.Events() {
+		if event == "CREATE TABLE a" || event == "CREATE TABLE b" {
+			applied = append(applied, event)
+		}
+	}
+
+	if len(applied) != 2 || applied[0] != "CREATE TABLE a" || applied[1] != "CREATE TABLE b" {
+		t.Errorf("expected migrations to be applied once in order, got %v", applied)
+	}
+
+	events := fake.Events()
+	if len(events) == 0 || !strings.HasPrefix(events[0], "SELECT pg_advisory_lock") {
+		t.Errorf("expected the migrations lock to be taken first, got %v", events)
+	}
+	if last := events[len(events)-1]; !strings.HasPrefix(last, "SELECT pg_advisory_unlock") {
+		t.Errorf("expected the migrations lock to be released last, got '%s'", last)
+	}
+}
+
+func TestMigrateFailed(t *testing.T) {
+	migrationErr := errors.New("syntax error")
+	db, fake := newFakeDB(nil, nil, migrationErr)
+	defer db.Close()
+
+	migrations := fstest.MapFS{
+		"migrations/001_create_a.sql": {Data: []byte("CREATE TABLE")},
+	}
+
+	err := sql.Migrate(context.Background(), db, migrations, "migrations")
+	if !errors.Is(err, sql.ErrMigrationFailed) || !errors.Is(err, migrationErr) {
+		t.Errorf("expected error '%s', got '%v'", sql.ErrMigrationFailed, err)
+	}
+
+	events := fake.Events()
+	if last := events[len(events)-1]; !strings.HasPrefix(last, "SELECT pg_advisory_unlock") {
+		t.Errorf("expected the migrations lock to be released after a failure, got '%s'", last)
+	}
+}
@@ -0,0 +1,81 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+)
+
+type Rows = sql.Rows
+
+const (
+	// Page size used when the cursor has no limit
+	DefaultPageSize = 20
+)
+
+// Position of a page in a keyset paginated query
+type Cursor struct {
+	// Ordered column value of the last item of the previous page, nil for the first page
+	After any
+	Limit int
+}
+
+// Items of a page and the cursor of the next one, nil on the last page
+type Page[T any] struct {
+	Items      []T
+	NextCursor *Cursor
+}
+
+// Runs a keyset paginated query, which receives the cursor's After value as
+// $1 and the page size as $2, e.g.
+//
+//	SELECT id, name FROM items WHERE ($1::text IS NULL OR id > $1) ORDER BY id LIMIT $2
+//
+// The key function returns the ordered column value of an item
+func Paginate[T any](
+	ctx context.Context,
+	db *DB,
+	query string,
+	cursor Cursor,
+	scan func(*Rows) (T, error),
+	key func(T) any,
+) (Page[T], error) {
+	limit := cursor.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+
+	// One extra row is fetched to know whether there is a next page
+	rows, err := db.QueryContext(ctx, query, cursor.After, limit+1)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	defer rows.Close()
+
+	items := make([]T, 0, limit)
+	hasNext := false
+	for rows.Next() {
+		if len(items) == limit {
+			hasNext = true
+			break
+		}
+
+		item, err := scan(rows)
+		if err != nil {
+			return Page[T]{}, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[T]{}, err
+	}
+
+	page := Page[T]{Items: items}
+	if hasNext {
+		page.NextCursor = &Cursor{
+			After: key(items[len(items)-1]),
+			Limit: limit,
+		}
+	}
+
+	return page, nil
+}
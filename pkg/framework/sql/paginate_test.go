@@ -0,0 +1,65 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"slices"
+	"testing"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/sql"
+)
+
+const listItemsQuery = "SELECT id FROM items WHERE ($1::text IS NULL OR id > $1) ORDER BY id LIMIT $2"
+
+func TestPaginate(t *testing.T) {
+	db, fake := newFakeDB()
+	defer db.Close()
+
+	ids := []string{"1", "2", "3", "4", "5"}
+	fake.queries = map[string]func(args []driver.NamedValue) []string{
+		listItemsQuery: func(args []driver.NamedValue) []string {
+			after, _ := args[0].Value.(string)
+			limit := int(args[1].Value.(int64))
+
+			var results []string
+			for _, id := range ids {
+				if id > after && len(results) < limit {
+					results = append(results, id)
+				}
+			}
+
+			return results
+		},
+	}
+
+	scan := func(rows *sql.Rows) (string, error) {
+		var id string
+		err := rows.Scan(&id)
+		return id, err
+	}
+	key := func(id string) any {
+		return id
+	}
+
+	var pages [][]string
+	cursor := &sql.Cursor{Limit: 2}
+	for cursor != nil {
+		page, err := sql.Paginate(context.Background(), db, listItemsQuery, *cursor, scan, key)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		pages = append(pages, page.Items)
+		cursor = page.NextCursor
+	}
+
+	expectedPages := [][]string{{"1", "2"}, {"3", "4"}, {"5"}}
+	if len(pages) != len(expectedPages) {
+		t.Fatalf("expected %d pages, got %v", len(expectedPages), pages)
+	}
+	for i, page := range pages {
+		if !slices.Equal(page, expectedPages[i]) {
+			t.Errorf("expected page %d to be %v, got %v", i, expectedPages[i], page)
+		}
+	}
+}
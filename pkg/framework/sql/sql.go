@@ -1,38 +1,215 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
 type DB = sql.DB
 
+var (
+	ErrConnectionFailed = errors.New("could not connect to the database")
+)
+
 type Conf struct {
+	// Registered driver name, defaults to DefaultDriver when empty
+	Driver string
+
 	Host     string
 	Port     string
 	Name     string
 	User     string
 	Password string
+
+	// TLS mode of the connection, defaults to SSLModeDisable when empty
+	SSLMode string
+
+	// Optional extra connection parameters, e.g. connect_timeout
+	Params map[string]string
+
+	// Connection pool limits, defaults apply when zero
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// Optional read-only replica configuration
+	Replica *Conf
+}
+
+// Pair of connections for write and read-only operations
+type DBPair struct {
+	Writer *DB
+	Reader *DB
 }
 
 func NewDB(
 	conf Conf,
 ) *sql.DB {
-	connStr := fmt.Sprintf(
-		"postgresql://%s:%s@%s:%s/%s?sslmode=disable",
-		conf.User,
-		conf.Password,
-		conf.Host,
-		conf.Port,
-		conf.Name,
-	)
-
-	db, err := sql.Open("pgx", connStr)
+	db, err := sql.Open(driverName(conf), ConnectionString(conf))
 	if err != nil {
 		panic(err)
 	}
+	configurePool(db, conf)
 
 	return db
 }
+
+// Creates a connection and verifies the database is reachable
+func NewDBWithContext(
+	ctx context.Context,
+	conf Conf,
+) (*sql.DB, error) {
+	db, err := sql.Open(driverName(conf), ConnectionString(conf))
+	if err != nil {
+		return nil, err
+	}
+	configurePool(db, conf)
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultPingTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+
+	return db, nil
+}
+
+// Creates the writer connection and the reader connection, the reader falls
+// back to the writer when no replica is configured
+func NewDBPair(
+	conf Conf,
+) DBPair {
+	writer := NewDB(conf)
+	if conf.Replica == nil {
+		return DBPair{
+			Writer: writer,
+			Reader: writer,
+		}
+	}
+
+	return DBPair{
+		Writer: writer,
+		Reader: NewDB(*conf.Replica),
+	}
+}
+
+// Creates the connection pair and verifies both databases are reachable
+func NewDBPairWithContext(
+	ctx context.Context,
+	conf Conf,
+) (DBPair, error) {
+	writer, err := NewDBWithContext(ctx, conf)
+	if err != nil {
+		return DBPair{}, err
+	}
+	if conf.Replica == nil {
+		return DBPair{
+			Writer: writer,
+			Reader: writer,
+		}, nil
+	}
+
+	reader, err := NewDBWithContext(ctx, *conf.Replica)
+	if err != nil {
+		writer.Close()
+		return DBPair{}, err
+	}
+
+	return DBPair{
+		Writer: writer,
+		Reader: reader,
+	}, nil
+}
+
+// Closes both connections, the shared one only once
+func (p DBPair) Close() error {
+	err := p.Writer.Close()
+	if p.Reader != p.Writer {
+		err = errors.Join(err, p.Reader.Close())
+	}
+
+	return err
+}
+
+const (
+	// Driver registered by the stdlib package of pgx
+	DefaultDriver = "pgx"
+
+	// Default connection pool limits
+	DefaultMaxOpenConns    = 25
+	DefaultMaxIdleConns    = 5
+	DefaultConnMaxLifetime = 30 * time.Minute
+
+	// Maximum time to wait for the database to respond on creation
+	DefaultPingTimeout = 5 * time.Second
+)
+
+func driverName(conf Conf) string {
+	if conf.Driver == "" {
+		return DefaultDriver
+	}
+
+	return conf.Driver
+}
+
+func configurePool(db *sql.DB, conf Conf) {
+	maxOpenConns := conf.MaxOpenConns
+	if maxOpenConns == 0 {
+		maxOpenConns = DefaultMaxOpenConns
+	}
+
+	maxIdleConns := conf.MaxIdleConns
+	if maxIdleConns == 0 {
+		maxIdleConns = DefaultMaxIdleConns
+	}
+
+	connMaxLifetime := conf.ConnMaxLifetime
+	if connMaxLifetime == 0 {
+		connMaxLifetime = DefaultConnMaxLifetime
+	}
+
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(connMaxLifetime)
+}
+
+const (
+	// TLS modes
+	SSLModeDisable    = "disable"
+	SSLModeRequire    = "require"
+	SSLModeVerifyFull = "verify-full"
+)
+
+// Builds the connection string of the given configuration
+func ConnectionString(conf Conf) string {
+	params := url.Values{}
+	for key, value := range conf.Params {
+		params.Set(key, value)
+	}
+
+	sslMode := conf.SSLMode
+	if sslMode == "" {
+		sslMode = SSLModeDisable
+	}
+	params.Set("sslmode", sslMode)
+
+	// Escape the credentials and database name, which may contain URL delimiters
+	connURL := url.URL{
+		Scheme:   "postgresql",
+		User:     url.UserPassword(conf.User, conf.Password),
+		Host:     net.JoinHostPort(conf.Host, conf.Port),
+		Path:     "/" + conf.Name,
+		RawQuery: params.Encode(),
+	}
+
+	return connURL.String()
+}
@@ -0,0 +1,93 @@
+package sql
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/log"
+)
+
+// Log field naming the pool of the logged statistics
+const PoolKey = "pool"
+
+// Connection pool statistics
+type PoolStats struct {
+	MaxOpenConnections int           `json:"max_open_connections"`
+	OpenConnections    int           `json:"open_connections"`
+	InUse              int           `json:"in_use"`
+	Idle               int           `json:"idle"`
+	WaitCount          int64         `json:"wait_count"`
+	WaitDuration       time.Duration `json:"wait_duration"`
+	MaxIdleClosed      int64         `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64         `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64         `json:"max_lifetime_closed"`
+}
+
+// Returns the connection pool statistics of the given connection
+func Stats(db *DB) PoolStats {
+	stats := db.Stats()
+
+	return PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDuration:       stats.WaitDuration,
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+}
+
+// Logs the connection pool statistics every interval until the returned
+// function is called
+func StartStatsLogger(
+	db *DB,
+	logger *log.Logger,
+	interval time.Duration,
+) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				logger.Info("SQL connection pool stats", Stats(db))
+			}
+		}
+	}()
+
+	var stopOnce sync.Once
+	return func() {
+		stopOnce.Do(func() {
+			close(done)
+		})
+	}
+}
+
+// Logs the connection pool statistics of the writer and, when it's a different
+// pool, of the reader every interval until the returned function is called.
+// The entries are told apart by their pool field
+func StartPairStatsLogger(
+	dbPair DBPair,
+	logger *log.Logger,
+	interval time.Duration,
+) func() {
+	stops := []func(){
+		StartStatsLogger(dbPair.Writer, logger.With(map[string]any{PoolKey: "writer"}), interval),
+	}
+	if dbPair.Reader != dbPair.Writer {
+		stops = append(stops, StartStatsLogger(dbPair.Reader, logger.With(map[string]any{PoolKey: "reader"}), interval))
+	}
+
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
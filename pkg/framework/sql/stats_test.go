@@ -0,0 +1,95 @@
+package sql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/log"
+	"github.com/sergioneiravargas/template-go/pkg/framework/sql"
+)
+
+func TestStats(t *testing.T) {
+	db, _ := newFakeDB()
+	defer db.Close()
+
+	if stats := sql.Stats(db); stats.OpenConnections != 0 {
+		t.Errorf("expected no open connections, got %d", stats.OpenConnections)
+	}
+
+	if _, err := db.Exec("SELECT 1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stats := sql.Stats(db)
+	if stats.OpenConnections != 1 {
+		t.Errorf("expected 1 open connection, got %d", stats.OpenConnections)
+	}
+	if stats.Idle != 1 {
+		t.Errorf("expected 1 idle connection, got %d", stats.Idle)
+	}
+}
+
+func TestStartStatsLogger(t *testing.T) {
+	db, _ := newFakeDB()
+	defer db.Close()
+
+	logger, records := log.NewTestLogger()
+	stop := sql.StartStatsLogger(db, logger, 10*time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for len(records.WithMessage("SQL connection pool stats")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+	stop()
+
+	statsRecords := records.WithMessage("SQL connection pool stats")
+	if len(statsRecords) == 0 {
+		t.Fatalf("expected stats to be logged")
+	}
+	if _, ok := statsRecords[0].Fields[log.ContextKey].(sql.PoolStats); !ok {
+		t.Errorf("expected logged context to be the pool stats, got %T", statsRecords[0].Fields[log.ContextKey])
+	}
+}
+
+func TestStartPairStatsLogger(t *testing.T) {
+	writer, _ := newFakeDB()
+	defer writer.Close()
+	reader, _ := newFakeDB()
+	defer reader.Close()
+
+	logger, records := log.NewTestLogger()
+	stop := sql.StartPairStatsLogger(sql.DBPair{Writer: writer, Reader: reader}, logger, 10*time.Millisecond)
+
+	loggedPools := func() map[any]bool {
+		pools := make(map[any]bool)
+		for _, record := range records.WithMessage("SQL connection pool stats") {
+			pools[record.Fields[sql.PoolKey]] = true
+		}
+
+		return pools
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(loggedPools()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+
+	if pools := loggedPools(); !pools["writer"] || !pools["reader"] {
+		t.Errorf("expected stats of the writer and reader pools to be logged, got %v", pools)
+	}
+
+	// A pair sharing its pool logs it once
+	records.Reset()
+	stop = sql.StartPairStatsLogger(sql.DBPair{Writer: writer, Reader: writer}, logger, 10*time.Millisecond)
+	deadline = time.Now().Add(time.Second)
+	for len(loggedPools()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+
+	if pools := loggedPools(); pools["reader"] {
+		t.Errorf("expected a shared pool to be logged only as the writer, got %v", pools)
+	}
+}
@@ -0,0 +1,90 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type Tx = sql.Tx
+
+var (
+	ErrRollbackFailed = errors.New("could not roll back the transaction")
+)
+
+// Runs the given function in a transaction, committing it when the function
+// succeeds and rolling it back otherwise
+func WithTx(
+	ctx context.Context,
+	db *DB,
+	fn func(tx *Tx) error,
+) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("%w: %w", ErrRollbackFailed, rollbackErr))
+		}
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const (
+	// Postgres error codes of transactions that can be retried
+	SerializationFailureCode = "40001"
+	DeadlockDetectedCode     = "40P01"
+
+	// Delay before the first retry, doubled on each attempt
+	DefaultRetryBackoff = 10 * time.Millisecond
+)
+
+// Runs the given function in a transaction like WithTx, retrying the whole
+// transaction up to maxAttempts times when it fails with a retryable error
+func RetryTx(
+	ctx context.Context,
+	db *DB,
+	maxAttempts int,
+	fn func(tx *Tx) error,
+) error {
+	backoff := DefaultRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := WithTx(ctx, db, fn)
+		if err == nil || attempt >= maxAttempts || !IsRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Reports whether the given error is a transient Postgres error
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Code == SerializationFailureCode || pgErr.Code == DeadlockDetectedCode
+}
@@ -0,0 +1,127 @@
+package sql_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sergioneiravargas/template-go/pkg/framework/sql"
+)
+
+func TestWithTxCommit(t *testing.T) {
+	db, fake := newFakeDB()
+	defer db.Close()
+
+	err := sql.WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedEvents := []string{"begin", "INSERT", "commit"}
+	if events := fake.Events(); !slices.Equal(events, expectedEvents) {
+		t.Errorf("expected events %v, got %v", expectedEvents, events)
+	}
+}
+
+func TestWithTxRollback(t *testing.T) {
+	execErr := errors.New("exec failed")
+	db, fake := newFakeDB(execErr)
+	defer db.Close()
+
+	err := sql.WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT")
+		return err
+	})
+	if !errors.Is(err, execErr) {
+		t.Errorf("expected error '%s', got '%v'", execErr, err)
+	}
+
+	expectedEvents := []string{"begin", "INSERT", "rollback"}
+	if events := fake.Events(); !slices.Equal(events, expectedEvents) {
+		t.Errorf("expected events %v, got %v", expectedEvents, events)
+	}
+}
+
+func TestWithTxRollbackFailed(t *testing.T) {
+	callbackErr := errors.New("callback failed")
+	rollbackErr := errors.New("rollback failed")
+	db, fake := newFakeDB()
+	fake.rollbackErr = rollbackErr
+	defer db.Close()
+
+	err := sql.WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		return callbackErr
+	})
+	if !errors.Is(err, callbackErr) {
+		t.Errorf("expected error '%s', got '%v'", callbackErr, err)
+	}
+	if !errors.Is(err, sql.ErrRollbackFailed) || !errors.Is(err, rollbackErr) {
+		t.Errorf("expected error '%s', got '%v'", sql.ErrRollbackFailed, err)
+	}
+}
+
+func TestRetryTx(t *testing.T) {
+	db, fake := newFakeDB(&pgconn.PgError{Code: sql.SerializationFailureCode})
+	defer db.Close()
+
+	attempts := 0
+	err := sql.RetryTx(context.Background(), db, 3, func(tx *sql.Tx) error {
+		attempts++
+		_, err := tx.Exec("UPDATE")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+
+	expectedEvents := []string{"begin", "UPDATE", "rollback", "begin", "UPDATE", "commit"}
+	if events := fake.Events(); !slices.Equal(events, expectedEvents) {
+		t.Errorf("expected events %v, got %v", expectedEvents, events)
+	}
+}
+
+func TestRetryTxNotRetryable(t *testing.T) {
+	execErr := &pgconn.PgError{Code: "23505"}
+	db, _ := newFakeDB(execErr)
+	defer db.Close()
+
+	attempts := 0
+	err := sql.RetryTx(context.Background(), db, 3, func(tx *sql.Tx) error {
+		attempts++
+		_, err := tx.Exec("INSERT")
+		return err
+	})
+	if !errors.Is(err, execErr) {
+		t.Errorf("expected error '%s', got '%v'", execErr, err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestRetryTxMaxAttempts(t *testing.T) {
+	deadlockErr := &pgconn.PgError{Code: sql.DeadlockDetectedCode}
+	db, _ := newFakeDB(deadlockErr, deadlockErr, deadlockErr)
+	defer db.Close()
+
+	attempts := 0
+	err := sql.RetryTx(context.Background(), db, 2, func(tx *sql.Tx) error {
+		attempts++
+		_, err := tx.Exec("UPDATE")
+		return err
+	})
+	if !errors.Is(err, deadlockErr) {
+		t.Errorf("expected error '%s', got '%v'", deadlockErr, err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+}
@@ -0,0 +1,16 @@
+package timeout
+
+import (
+	"net/http"
+	"time"
+)
+
+// Middleware that cancels the request's context once the timeout elapses,
+// responding with 503 when the handler did not finish in time
+func Middleware(
+	timeout time.Duration,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, timeout, "Service unavailable")
+	}
+}
@@ -0,0 +1,61 @@
+package timeout_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sergioneiravargas/template-go/pkg/framework/timeout"
+)
+
+func TestMiddleware(t *testing.T) {
+	handlerErr := make(chan error, 1)
+	handler := timeout.Middleware(20 * time.Millisecond)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+				handlerErr <- r.Context().Err()
+			case <-time.After(time.Second):
+				handlerErr <- nil
+				w.Write([]byte("too late"))
+			}
+		},
+	))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if res.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status code %d, got %d", http.StatusServiceUnavailable, res.Code)
+	}
+
+	select {
+	case err := <-handlerErr:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected handler context error '%s', got '%v'", context.DeadlineExceeded, err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected handler context to be cancelled")
+	}
+}
+
+func TestMiddlewareInTime(t *testing.T) {
+	handler := timeout.Middleware(time.Second)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("in time"))
+		},
+	))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if res.Code != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, res.Code)
+	}
+	if res.Body.String() != "in time" {
+		t.Errorf("expected body 'in time', got '%s'", res.Body.String())
+	}
+}